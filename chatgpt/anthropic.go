@@ -0,0 +1,419 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/jkrebs-tr/goUtils/http"
+)
+
+// anthropicBaseURL is the root of the Anthropic API
+const anthropicBaseURL = "https://api.anthropic.com/v1"
+
+// Anthropic is the LLM for the Anthropic Messages API. Requests are translated from
+// the chat completions shape: system messages become the system prompt, tools and
+// tool calls become tool_use / tool_result blocks, and replies are translated back.
+// A json_schema ResponseFormat is passed on as an instruction in the system prompt.
+// Temperature is clamped to the Messages API range of 0 to 1. Of the optional parameters,
+// max tokens, top_p, stop and user are passed on; the others have no Messages API
+// equivalent and are ignored.
+type Anthropic struct {
+	APIKey    string       // sent in the x-api-key header
+	BaseURL   string       // defaults to https://api.anthropic.com/v1
+	Version   string       // anthropic-version header, defaults to 2023-06-01
+	MaxTokens int          // max_tokens for requests that don't set one, defaults to 4096
+	Model     string       // used for requests that don't name a model
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+	TopP        *float32           `json:"top_p,omitempty"`
+	Stop        []string           `json:"stop_sequences,omitempty"`
+	Metadata    map[string]string  `json:"metadata,omitempty"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice  map[string]string  `json:"tool_choice,omitempty"`
+	Stream      bool               `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ID        string           `json:"id,omitempty"`          // tool_use
+	Name      string           `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage  `json:"input,omitempty"`       // tool_use
+	ToolUseID string           `json:"tool_use_id,omitempty"` // tool_result
+	Content   string           `json:"content,omitempty"`     // tool_result
+	Source    *anthropicSource `json:"source,omitempty"`      // image, document
+}
+
+type anthropicSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema *JSONSchema `json:"input_schema"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage translates to Usage. input_tokens only counts the tokens after the last cache
+// breakpoint, so cache writes and reads are added to the prompt tokens, with cache reads
+// counted as cached prompt tokens.
+func (u anthropicUsage) usage() Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	usage := Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      prompt + u.OutputTokens,
+	}
+	if u.CacheReadInputTokens > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{CachedTokens: u.CacheReadInputTokens}
+	}
+	return usage
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicEvent is any of the server-sent events of a streamed message
+type anthropicEvent struct {
+	Type         string             `json:"type"`
+	Message      *anthropicResponse `json:"message,omitempty"`       // message_start
+	Index        int                `json:"index"`                   // content_block_*
+	ContentBlock *anthropicBlock    `json:"content_block,omitempty"` // content_block_start
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`         // text_delta
+		PartialJSON string `json:"partial_json"` // input_json_delta
+		StopReason  string `json:"stop_reason"`  // message_delta
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage,omitempty"` // message_delta
+	Error *APIError       `json:"error,omitempty"`
+}
+
+func (a *Anthropic) Chat(ctx context.Context, req ChatRequest) (Response, error) {
+	body, err := a.request(req)
+	if err != nil {
+		return Response{}, err
+	}
+
+	var response anthropicResponse
+	err = http.DoCtx(ctx, clientOrDefault(a.Client), "POST", a.url(), &response, body, nil, a.headers())
+	if err != nil {
+		return Response{}, err
+	}
+	return response.toResponse(), nil
+}
+
+func (a *Anthropic) Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		body, err := a.request(req)
+		if err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		body.Stream = true
+
+		events := http.TypedEvents[anthropicEvent](ctx, clientOrDefault(a.Client), http.SSERequest{
+			Method:  "POST",
+			URL:     a.url(),
+			Body:    body,
+			Headers: a.headers(),
+		})
+
+		var id, model string
+		var usage Usage
+		toolIndex := map[int]int{} // content block index -> tool call index
+		for event, err := range events {
+			if err != nil {
+				yield(Chunk{}, err)
+				return
+			}
+
+			chunk := Chunk{ID: id, Object: "chat.completion.chunk", Model: model}
+			ev := event.Value
+			switch ev.Type {
+			case "message_start":
+				if ev.Message != nil {
+					id, model = ev.Message.ID, ev.Message.Model
+					usage = ev.Message.Usage.usage()
+				}
+				chunk.ID, chunk.Model = id, model
+				chunk.Choices = []ChunkChoice{{Delta: Delta{Role: "assistant"}}}
+
+			case "content_block_start":
+				if ev.ContentBlock == nil || ev.ContentBlock.Type != "tool_use" {
+					continue
+				}
+				toolIndex[ev.Index] = len(toolIndex)
+				chunk.Choices = []ChunkChoice{{Delta: Delta{ToolCalls: []ToolCall{{
+					Index:    toolIndex[ev.Index],
+					ID:       ev.ContentBlock.ID,
+					Type:     "function",
+					Function: FunctionCall{Name: ev.ContentBlock.Name},
+				}}}}}
+
+			case "content_block_delta":
+				switch ev.Delta.Type {
+				case "text_delta":
+					chunk.Choices = []ChunkChoice{{Delta: Delta{Content: ev.Delta.Text}}}
+				case "input_json_delta":
+					chunk.Choices = []ChunkChoice{{Delta: Delta{ToolCalls: []ToolCall{{
+						Index:    toolIndex[ev.Index],
+						Function: FunctionCall{Arguments: ev.Delta.PartialJSON},
+					}}}}}
+				default:
+					continue
+				}
+
+			case "message_delta":
+				if ev.Usage != nil {
+					usage.CompletionTokens = ev.Usage.OutputTokens
+					usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				}
+				chunk.Choices = []ChunkChoice{{FinishReason: anthropicFinishReason(ev.Delta.StopReason)}}
+
+			case "message_stop":
+				final := usage
+				chunk.Usage = &final
+				yield(chunk, nil)
+				return
+
+			case "error":
+				if ev.Error == nil {
+					ev.Error = &APIError{Message: event.Data}
+				}
+				yield(Chunk{}, ev.Error)
+				return
+
+			default:
+				// ping and content_block_stop carry nothing to pass on
+				continue
+			}
+
+			if !yield(chunk, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (a *Anthropic) url() string {
+	baseURL := a.BaseURL
+	if baseURL == "" {
+		baseURL = anthropicBaseURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + "/messages"
+}
+
+func (a *Anthropic) headers() map[string]string {
+	version := a.Version
+	if version == "" {
+		version = "2023-06-01"
+	}
+	return map[string]string{
+		"x-api-key":         a.APIKey,
+		"anthropic-version": version,
+		"Content-Type":      "application/json",
+	}
+}
+
+// request translates a chat completions request to the Messages API
+func (a *Anthropic) request(req ChatRequest) (anthropicRequest, error) {
+	body := anthropicRequest{
+		Model:       withModel(req, a.Model).Model,
+		MaxTokens:   a.MaxTokens,
+		Temperature: min(max(req.Temperature, 0), 1), // chat completions allow up to 2
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+	}
+	switch {
+	case req.MaxCompletionTokens > 0:
+		body.MaxTokens = req.MaxCompletionTokens
+	case req.MaxTokens > 0:
+		body.MaxTokens = req.MaxTokens
+	case body.MaxTokens <= 0:
+		body.MaxTokens = 4096
+	}
+	if req.User != "" {
+		body.Metadata = map[string]string{"user_id": req.User}
+	}
+
+	var system []string
+	for _, msg := range req.Messages {
+		var role string
+		var blocks []anthropicBlock
+
+		switch msg.Role {
+		case "system", "developer":
+			system = append(system, msg.Text())
+			continue
+		case "tool":
+			role = "user"
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Text()})
+		default:
+			role = msg.Role
+			if msg.Content != "" && len(msg.Parts) == 0 {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+			}
+			for _, part := range msg.Parts {
+				block, err := anthropicContentBlock(part)
+				if err != nil {
+					return anthropicRequest{}, err
+				}
+				blocks = append(blocks, block)
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				if !json.Valid(input) {
+					return anthropicRequest{}, fmt.Errorf("Error Translating Tool Call %s: arguments are not valid JSON", call.ID)
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		// the API wants alternating roles, so consecutive messages are merged
+		if n := len(body.Messages); n > 0 && body.Messages[n-1].Role == role {
+			body.Messages[n-1].Content = append(body.Messages[n-1].Content, blocks...)
+		} else {
+			body.Messages = append(body.Messages, anthropicMessage{Role: role, Content: blocks})
+		}
+	}
+
+	if format := req.ResponseFormat; format != nil && format.JSONSchema != nil {
+		schema, err := json.Marshal(format.JSONSchema.Schema)
+		if err != nil {
+			return anthropicRequest{}, fmt.Errorf("Error Marshaling Response Schema: %w", err)
+		}
+		system = append(system, "Reply with only a JSON object, no prose or code fences, matching this JSON Schema: "+string(schema))
+	}
+	body.System = strings.Join(system, "\n\n")
+
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if schema == nil {
+			schema = &JSONSchema{Type: "object"}
+		}
+		body.Tools = append(body.Tools, anthropicTool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: schema})
+	}
+	body.ToolChoice = anthropicToolChoice(req.ToolChoice)
+	return body, nil
+}
+
+// anthropicContentBlock translates a content part to a text, image or document block
+func anthropicContentBlock(part ContentPart) (anthropicBlock, error) {
+	switch {
+	case part.Type == "text":
+		return anthropicBlock{Type: "text", Text: part.Text}, nil
+	case part.Type == "image_url" && part.ImageURL != nil:
+		return anthropicBlock{Type: "image", Source: anthropicURLSource(part.ImageURL.URL)}, nil
+	case part.Type == "file" && part.File != nil:
+		if part.File.FileID != "" {
+			return anthropicBlock{Type: "document", Source: &anthropicSource{Type: "file", FileID: part.File.FileID}}, nil
+		}
+		return anthropicBlock{Type: "document", Source: anthropicURLSource(part.File.FileData)}, nil
+	}
+	return anthropicBlock{}, fmt.Errorf("Error Translating Message: %s content is not supported by Anthropic", part.Type)
+}
+
+// anthropicURLSource turns a data URL into a base64 source and anything else into a url source
+func anthropicURLSource(url string) *anthropicSource {
+	if mediaType, data, ok := parseDataURL(url); ok {
+		return &anthropicSource{Type: "base64", MediaType: mediaType, Data: data}
+	}
+	return &anthropicSource{Type: "url", URL: url}
+}
+
+// anthropicToolChoice translates "auto", "none", "required" and ToolChoiceFunction
+func anthropicToolChoice(choice any) map[string]string {
+	switch c := choice.(type) {
+	case string:
+		switch c {
+		case "auto", "none":
+			return map[string]string{"type": c}
+		case "required":
+			return map[string]string{"type": "any"}
+		}
+	case map[string]any:
+		if fn, ok := c["function"].(map[string]string); ok {
+			return map[string]string{"type": "tool", "name": fn["name"]}
+		}
+	}
+	return nil
+}
+
+// toResponse translates a Messages API reply to a chat completions Response
+func (r anthropicResponse) toResponse() Response {
+	msg := Message{Role: "assistant"}
+	var text []string
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	msg.Content = strings.Join(text, "")
+
+	usage := r.Usage.usage()
+	return Response{
+		ID:      r.ID,
+		Object:  "chat.completion",
+		Model:   r.Model,
+		Choices: []Choice{{Message: msg, FinishReason: anthropicFinishReason(r.StopReason)}},
+		Usage:   &usage,
+	}
+}
+
+// anthropicFinishReason maps a stop_reason to the chat completions finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	}
+	return stopReason
+}
@@ -0,0 +1,59 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// anthropicServer answers every message with usage and records the temperature it was sent
+func anthropicServer(t *testing.T, temperature *float32) *Anthropic {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		*temperature = body.Temperature
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","model":"claude-test","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"cache_creation_input_tokens":200,"cache_read_input_tokens":1000,"output_tokens":5}}`)
+	}))
+	t.Cleanup(server.Close)
+	return &Anthropic{APIKey: "key", BaseURL: server.URL, Model: "claude-test"}
+}
+
+func TestAnthropicUsage(t *testing.T) {
+	var temperature float32
+	llm := anthropicServer(t, &temperature)
+
+	resp, err := llm.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := resp.Usage
+	if usage.PromptTokens != 1210 || usage.CompletionTokens != 5 || usage.TotalTokens != 1215 {
+		t.Errorf("usage = %+v, want 1210 prompt tokens including cache writes and reads", usage)
+	}
+	if usage.PromptTokensDetails == nil || usage.PromptTokensDetails.CachedTokens != 1000 {
+		t.Errorf("prompt details = %+v, want 1000 cached tokens", usage.PromptTokensDetails)
+	}
+}
+
+func TestAnthropicClampsTemperature(t *testing.T) {
+	var temperature float32
+	llm := anthropicServer(t, &temperature)
+
+	for _, tt := range []struct{ sent, want float32 }{{1.5, 1}, {2, 1}, {0.3, 0.3}, {-1, 0}} {
+		_, err := llm.Chat(context.Background(), ChatRequest{Temperature: tt.sent, Messages: []Message{{Role: "user", Content: "hi"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if temperature != tt.want {
+			t.Errorf("temperature %v was sent as %v, want %v", tt.sent, temperature, tt.want)
+		}
+	}
+}
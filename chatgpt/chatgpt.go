@@ -1,13 +1,48 @@
 package chatgpt
 
 import (
-	"fmt"
-
-	"github.com/jkrebs-tr/goUtils/http"
+	"context"
 )
 
+// Chat sends messages to ChatGPT, or the LLM given with WithLLM, and returns the response.
+// Every chat completions parameter can be set with a RequestOption; unset parameters use
+// the provider's defaults, and the temperature is 1. The key defaults to OPENAI_API_KEY.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - messages: The messages/context to send to gpt
+//   - opts: Request options (WithModel, WithKey, WithLLM, WithTemperature, WithMaxTokens, WithTopP,
+//     WithStop, WithSeed, WithN, WithPresencePenalty, WithFrequencyPenalty, WithLogitBias, WithUser,
+//     WithLogprobs, WithTools, WithToolChoice, WithResponseFormat, ...)
+//
+// Returns:
+//   - Response: The response with its choices and usage staticstics
+//   - Error: Any errors that occur during execution
+//
+// Example Usage:
+//
+//	resp, err := Chat(ctx, messages,
+//		WithModel("gpt-4o"),
+//		WithMaxTokens(200),
+//		WithStop("\n\n"),
+//		WithSeed(42),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if resp.Choices[0].Truncated() {
+//		log.Println("reply was cut off at 200 tokens")
+//	}
+//	fmt.Println(resp.Choices[0].Message.Content)
+func Chat(ctx context.Context, messages []Message, opts ...RequestOption) (Response, error) {
+	return sendChat(ctx, ChatRequest{Messages: messages, Temperature: 1}, "", opts)
+}
+
 // Send a request to ChatGPT and return the response - functions similarly to a normal chatGPT chat
 //
+// Deprecated: Use Chat with WithModel, WithTemperature and WithKey, which also covers
+// the remaining request parameters.
+//
 // Parameters:
 //   - model: The GPT model you want to use (gpt-4)
 //   - messages: The messages/context to send to gpt
@@ -32,23 +67,32 @@ import (
 //
 //	fmt.Println("Assistant:", resp.Choices[0].Message.Content)
 func SendRequest(model string, messages []Message, tmp float32, key string) (Response, error) {
-	url := "https://api.openai.com/v1/chat/completions"
-	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", key),
-		"Content-Type":  "application/json",
-	}
+	return SendRequestCtx(context.Background(), model, messages, tmp, key)
+}
 
+// SendRequestCtx is like SendRequest but honors cancellation and deadlines on ctx,
+// aborting the in-flight request to OpenAI when ctx is done.
+//
+// Deprecated: Use Chat.
+//
+// Example Usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+//	defer cancel()
+//
+//	resp, err := SendRequestCtx(ctx, "gpt-4", messages, 0.7, os.Getenv("OPENAI_API_KEY"))
+func SendRequestCtx(ctx context.Context, model string, messages []Message, tmp float32, key string) (Response, error) {
 	body := ChatRequest{
 		Model:       model,
 		Messages:    messages,
 		Temperature: tmp,
 	}
 
-	var response Response
-	err := http.MakeRequest("POST", url, &response, body, nil, headers)
-	if err != nil {
-		return Response{}, err
-	}
+	return sendChat(ctx, body, key, nil)
+}
 
-	return response, nil
+// sendChat applies opts to body and sends it to the configured provider
+func sendChat(ctx context.Context, body ChatRequest, key string, opts []RequestOption) (Response, error) {
+	cfg := newRequestConfig(body, key, opts)
+	return cfg.provider().Chat(ctx, cfg.req)
 }
@@ -0,0 +1,221 @@
+package chatgpt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ContentPart is one part of a multimodal Message: text, an image, audio or a file.
+type ContentPart struct {
+	Type       string      `json:"type"` // "text", "image_url", "input_audio" or "file"
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty"`
+	InputAudio *InputAudio `json:"input_audio,omitempty"`
+	File       *InputFile  `json:"file,omitempty"`
+}
+
+// ImageURL is an image given by URL or base64 data URL.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // "low", "high" or "auto" (the default)
+}
+
+// InputAudio is base64 encoded audio for audio capable models.
+type InputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"` // "wav" or "mp3"
+}
+
+// InputFile is a file, such as a PDF, given inline as a data URL or by uploaded file ID.
+type InputFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"` // base64 data URL
+	FileID   string `json:"file_id,omitempty"`
+}
+
+// MarshalJSON encodes Parts as the content array when set, and Content as a string otherwise.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []ContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+// UnmarshalJSON decodes string content into Content and array content into Parts.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.message)
+
+	switch content := strings.TrimSpace(string(raw.Content)); {
+	case content == "" || content == "null":
+	case content[0] == '[':
+		return json.Unmarshal(raw.Content, &m.Parts)
+	default:
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+	return nil
+}
+
+// Text returns Content, or the text parts joined by newlines for a multimodal message.
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var text []string
+	for _, part := range m.Parts {
+		if part.Type == "text" {
+			text = append(text, part.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// UserMessage builds a multimodal user message from parts.
+//
+// Example Usage:
+//
+//	image, err := ImageFromFile("invoice.png", "high")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	messages := []Message{
+//		{Role: "system", Content: "Extract the invoice details."},
+//		UserMessage(TextPart("Here is the scanned invoice."), image),
+//	}
+//	resp, err := Chat(ctx, messages, WithModel("gpt-4o"))
+func UserMessage(parts ...ContentPart) Message {
+	return Message{Role: "user", Parts: parts}
+}
+
+// TextPart is a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePart is an image content part for an http(s) or data URL.
+//
+// Parameters:
+//   - url: The image URL
+//   - detail: "low", "high", or "" for auto
+func ImagePart(url string, detail string) ContentPart {
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url, Detail: detail}}
+}
+
+// ImageFromBytes is an image content part sending data inline as a base64 data URL.
+// The image type (png, jpeg, gif or webp) is detected from the data.
+//
+// Returns:
+//   - ContentPart: The image part
+//   - Error: If data is not an image
+func ImageFromBytes(data []byte, detail string) (ContentPart, error) {
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return ContentPart{}, fmt.Errorf("Error Creating Image Part: data is %s, not an image", mimeType)
+	}
+	return ImagePart(DataURL(mimeType, data), detail), nil
+}
+
+// ImageFromFile reads a local image and returns it as a content part (see ImageFromBytes).
+//
+// Example Usage:
+//
+//	screenshot, err := ImageFromFile("screenshot.png", "low")
+func ImageFromFile(path string, detail string) (ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("Error Reading Image (%s): %w", path, err)
+	}
+	return ImageFromBytes(data, detail)
+}
+
+// AudioFromBytes is an audio content part in the given format ("wav" or "mp3").
+func AudioFromBytes(data []byte, format string) ContentPart {
+	return ContentPart{Type: "input_audio", InputAudio: &InputAudio{
+		Data:   base64.StdEncoding.EncodeToString(data),
+		Format: format,
+	}}
+}
+
+// AudioFromFile reads a local .wav or .mp3 file and returns it as a content part.
+func AudioFromFile(path string) (ContentPart, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != "wav" && format != "mp3" {
+		return ContentPart{}, fmt.Errorf("Error Creating Audio Part: %s is not a .wav or .mp3 file", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("Error Reading Audio (%s): %w", path, err)
+	}
+	return AudioFromBytes(data, format), nil
+}
+
+// FileFromBytes is a file content part sending data inline, e.g. a PDF. The type is
+// taken from the filename's extension.
+func FileFromBytes(filename string, data []byte) ContentPart {
+	mimeType := mime.TypeByExtension(filepath.Ext(filename))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return ContentPart{Type: "file", File: &InputFile{Filename: filename, FileData: DataURL(mimeType, data)}}
+}
+
+// FileFromFile reads a local file, e.g. a PDF, and returns it as a content part.
+//
+// Example Usage:
+//
+//	contract, err := FileFromFile("contract.pdf")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	messages := []Message{UserMessage(TextPart("Summarize the termination clauses."), contract)}
+func FileFromFile(path string) (ContentPart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("Error Reading File (%s): %w", path, err)
+	}
+	return FileFromBytes(filepath.Base(path), data), nil
+}
+
+// FileIDPart is a file content part for a file already uploaded to the provider.
+func FileIDPart(fileID string) ContentPart {
+	return ContentPart{Type: "file", File: &InputFile{FileID: fileID}}
+}
+
+// DataURL encodes data as a base64 data URL, e.g. "data:image/png;base64,iVBOR...".
+func DataURL(mimeType string, data []byte) string {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i] // drop parameters such as charset
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// parseDataURL splits a base64 data URL into its media type and encoded data
+func parseDataURL(url string) (mimeType string, data string, ok bool) {
+	rest, found := strings.CutPrefix(url, "data:")
+	if !found {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mimeType, found = strings.CutSuffix(header, ";base64")
+	return mimeType, data, found
+}
@@ -0,0 +1,248 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Conversation keeps the system prompt and message history of a multi-turn chat,
+// appending the assistant's replies automatically. When TokenBudget is set, old turns
+// are dropped (or summarized, with Summarize) so the prompt stays within the budget.
+//
+// A Conversation is safe for concurrent use, though turns are sent one at a time.
+type Conversation struct {
+	Model        string          `json:"model"`
+	Temperature  float32         `json:"temperature"`
+	SystemPrompt string          `json:"system_prompt,omitempty"`
+	Summary      string          `json:"summary,omitempty"` // summary of turns dropped from History
+	History      []Message       `json:"history"`           // every message after the system prompt
+	TokenBudget  int             `json:"token_budget,omitempty"`
+	Summarize    bool            `json:"summarize,omitempty"` // summarize old turns instead of dropping them
+	LLM          LLM             `json:"-"`                   // provider to send turns to, defaults to OpenAI with the key given
+	Options      []RequestOption `json:"-"`                   // further options for every request, e.g. WithMaxTokens
+
+	key string
+	mu  sync.Mutex
+}
+
+// summaryPrompt asks the model to condense the turns being dropped
+const summaryPrompt = "Summarize the conversation so far in a few sentences, keeping names, facts, decisions and open questions. Reply with the summary only."
+
+// NewConversation starts a conversation with the given model and system prompt.
+//
+// Parameters:
+//   - model: The GPT model you want to use (gpt-4)
+//   - systemPrompt: The system message sent before the history, "" for none
+//   - key: The openAPI key to use in the requests
+//
+// Returns:
+//   - *Conversation: The new conversation, with Temperature 0.7 and no token budget
+//
+// Example Usage:
+//
+//	conv := NewConversation("gpt-4", "You are a helpful assistant.", os.Getenv("OPENAI_API_KEY"))
+//	conv.TokenBudget = 6000
+//	conv.Summarize = true
+//
+//	reply, err := conv.Send(ctx, "Who wrote The Hobbit?")
+//	reply, err = conv.Send(ctx, "What else did he write?")
+//
+//	if err := conv.Save("chat.json"); err != nil {
+//		log.Fatal(err)
+//	}
+func NewConversation(model string, systemPrompt string, key string) *Conversation {
+	return &Conversation{
+		Model:        model,
+		Temperature:  0.7,
+		SystemPrompt: systemPrompt,
+		key:          key,
+	}
+}
+
+// LoadConversation resumes a conversation saved with Save.
+//
+// Example Usage:
+//
+//	conv, err := LoadConversation("chat.json", os.Getenv("OPENAI_API_KEY"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	reply, err := conv.Send(ctx, "Where were we?")
+func LoadConversation(path string, key string) (*Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error Reading Conversation: %w", err)
+	}
+	conv := &Conversation{}
+	if err := json.Unmarshal(data, conv); err != nil {
+		return nil, fmt.Errorf("Error Unmarshaling Conversation: %w", err)
+	}
+	conv.key = key
+	return conv, nil
+}
+
+// Save writes the conversation to path as JSON. The API key is not saved.
+func (c *Conversation) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("Error Marshaling Conversation: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("Error Writing Conversation: %w", err)
+	}
+	return nil
+}
+
+// Send adds a user message, sends the conversation and appends the assistant's reply,
+// returning its content. If the request fails the history and summary are left as they
+// were, including any turns that were dropped or summarized to fit the budget.
+func (c *Conversation) Send(ctx context.Context, content string) (string, error) {
+	resp, err := c.SendMessage(ctx, Message{Role: "user", Content: content})
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// SendMessage is like Send but takes a full Message and returns the whole Response.
+func (c *Conversation) SendMessage(ctx context.Context, msg Message) (Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// fit may drop or summarize turns, which a failed request must not lose
+	history, summary := c.History, c.Summary
+	restore := func() {
+		c.History, c.Summary = history, summary
+	}
+
+	c.History = append(c.History, msg)
+	if err := c.fit(ctx); err != nil {
+		restore()
+		return Response{}, err
+	}
+
+	resp, err := sendChat(ctx, ChatRequest{Model: c.Model, Messages: c.messages(), Temperature: c.Temperature}, c.key, c.options())
+	if err != nil {
+		restore()
+		return Response{}, err
+	}
+	if len(resp.Choices) == 0 {
+		restore()
+		return Response{}, fmt.Errorf("Error Sending Conversation: response has no choices")
+	}
+
+	c.History = append(c.History, resp.Choices[0].Message)
+	return resp, nil
+}
+
+// Messages returns the messages that are sent with the next turn: the system prompt,
+// the summary of earlier turns, if any, and the history.
+func (c *Conversation) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.messages()
+}
+
+// Reset clears the history and summary, keeping the system prompt.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.History = nil
+	c.Summary = ""
+}
+
+// options returns the request options for the conversation's provider
+func (c *Conversation) options() []RequestOption {
+	if c.LLM == nil {
+		return c.Options
+	}
+	return append([]RequestOption{WithLLM(c.LLM)}, c.Options...)
+}
+
+func (c *Conversation) messages() []Message {
+	messages := make([]Message, 0, len(c.History)+2)
+	if c.SystemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: c.SystemPrompt})
+	}
+	if c.Summary != "" {
+		messages = append(messages, Message{Role: "system", Content: "Summary of the earlier conversation: " + c.Summary})
+	}
+	return append(messages, c.History...)
+}
+
+// fit drops (or summarizes) the oldest turns until the prompt is within TokenBudget.
+// The latest turn is always kept.
+func (c *Conversation) fit(ctx context.Context) error {
+	if c.TokenBudget <= 0 {
+		return nil
+	}
+
+	// a new summary adds tokens of its own, so the budget is checked again after every cut
+	for countTokens(c.Model, c.messages()) > c.TokenBudget {
+		// find the first turn boundary (a user message) that brings the prompt within budget
+		cut := 0
+		for i := 1; i < len(c.History); i++ {
+			if c.History[i].Role != "user" {
+				continue
+			}
+			cut = i
+			rest := c.History[i:]
+			if countTokens(c.Model, append(c.systemMessages(), rest...)) <= c.TokenBudget {
+				break
+			}
+		}
+		if cut == 0 {
+			return nil
+		}
+
+		dropped := c.History[:cut]
+		if c.Summarize {
+			summary, err := c.summarize(ctx, dropped)
+			if err != nil {
+				return err
+			}
+			c.Summary = summary
+		}
+		c.History = append([]Message(nil), c.History[cut:]...)
+	}
+	return nil
+}
+
+// systemMessages returns the messages that precede the history
+func (c *Conversation) systemMessages() []Message {
+	all := c.messages()
+	return all[:len(all)-len(c.History)]
+}
+
+// summarize asks the model to fold the dropped turns into the running summary.
+// It goes to the conversation's provider but without Options such as tools or a
+// response format, which are meant for the conversation's own turns.
+func (c *Conversation) summarize(ctx context.Context, dropped []Message) (string, error) {
+	var transcript strings.Builder
+	if c.Summary != "" {
+		fmt.Fprintf(&transcript, "Earlier summary: %s\n\n", c.Summary)
+	}
+	for _, msg := range dropped {
+		fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, msg.Text())
+	}
+
+	messages := []Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: transcript.String()},
+	}
+	provider := newRequestConfig(ChatRequest{}, c.key, c.options()).provider()
+	resp, err := provider.Chat(ctx, ChatRequest{Model: c.Model, Messages: messages})
+	if err != nil {
+		return "", fmt.Errorf("Error Summarizing Conversation: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("Error Summarizing Conversation: response has no choices")
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,114 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeLLM answers summary requests from summaries, in order, and conversation turns with reply
+type fakeLLM struct {
+	summaries []string
+	reply     string
+	err       error
+	requests  []ChatRequest
+}
+
+func (f *fakeLLM) Chat(ctx context.Context, req ChatRequest) (Response, error) {
+	f.requests = append(f.requests, req)
+	content := f.reply
+	if len(req.Messages) > 0 && req.Messages[0].Content == summaryPrompt {
+		content, f.summaries = f.summaries[0], f.summaries[1:]
+	} else if f.err != nil {
+		return Response{}, f.err
+	}
+	return Response{Choices: []Choice{{Message: Message{Role: "assistant", Content: content}}}}, nil
+}
+
+func (f *fakeLLM) Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {}
+}
+
+// summarizing returns a conversation with two long turns and a budget that fits the
+// second one after a short summary of the first
+func summarizing(llm *fakeLLM) (*Conversation, Message) {
+	long := strings.Repeat("tell me more about the history of the shire ", 10)
+	conv := NewConversation("test-model", "", "")
+	conv.LLM = llm
+	conv.Summarize = true
+	conv.History = []Message{
+		{Role: "user", Content: "first " + long},
+		{Role: "assistant", Content: "first answer " + long},
+		{Role: "user", Content: "second " + long},
+		{Role: "assistant", Content: "second answer " + long},
+	}
+	next := Message{Role: "user", Content: "and then?"}
+	conv.Summary = "summary"
+	conv.TokenBudget = countTokens(conv.Model, append(conv.systemMessages(), conv.History[2], conv.History[3], next))
+	conv.Summary = ""
+	return conv, next
+}
+
+func TestConversationRechecksBudgetAfterSummary(t *testing.T) {
+	// the first summary is too long to fit, so the second turn has to go as well
+	llm := &fakeLLM{summaries: []string{strings.Repeat("a very long summary ", 100), "summary"}, reply: "ok"}
+	conv, next := summarizing(llm)
+
+	if _, err := conv.SendMessage(context.Background(), next); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.requests) != 3 {
+		t.Fatalf("sent %d requests, want 2 summaries and the turn", len(llm.requests))
+	}
+	sent := llm.requests[2].Messages
+	if tokens := countTokens(conv.Model, sent); tokens > conv.TokenBudget {
+		t.Errorf("sent %d tokens, budget is %d", tokens, conv.TokenBudget)
+	}
+	if conv.Summary != "summary" || len(conv.History) != 2 {
+		t.Errorf("summary = %q, history = %d messages, want the short summary and only the latest exchange", conv.Summary, len(conv.History))
+	}
+}
+
+func TestConversationRestoresHistoryOnError(t *testing.T) {
+	llm := &fakeLLM{summaries: []string{"summary"}, err: errors.New("boom")}
+	conv, next := summarizing(llm)
+	conv.Summary = "earlier"
+	history := append([]Message(nil), conv.History...)
+
+	if _, err := conv.SendMessage(context.Background(), next); err == nil {
+		t.Fatal("SendMessage() succeeded, want the provider error")
+	}
+	if len(llm.requests) != 2 {
+		t.Fatalf("sent %d requests, want the summary and the turn", len(llm.requests))
+	}
+	if !reflect.DeepEqual(conv.History, history) || conv.Summary != "earlier" {
+		t.Errorf("history = %+v, summary = %q, want both unchanged", conv.History, conv.Summary)
+	}
+}
+
+func TestConversationSummaryWithoutOptions(t *testing.T) {
+	llm := &fakeLLM{summaries: []string{"summary"}, reply: "ok"}
+	conv, next := summarizing(llm)
+	conv.Options = []RequestOption{
+		WithTools(Tool{Type: "function", Function: FunctionSpec{Name: "lookup"}}),
+		WithN(3),
+		WithResponseFormat(&ResponseFormat{Type: "json_object"}),
+	}
+
+	if _, err := conv.SendMessage(context.Background(), next); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.requests) != 2 {
+		t.Fatalf("sent %d requests, want the summary and the turn", len(llm.requests))
+	}
+	summary, turn := llm.requests[0], llm.requests[1]
+	if summary.Tools != nil || summary.N != 0 || summary.ResponseFormat != nil {
+		t.Errorf("summary request = %+v, want no tools, n or response_format", summary)
+	}
+	if len(turn.Tools) != 1 || turn.N != 3 || turn.ResponseFormat == nil {
+		t.Errorf("turn request = %+v, want the conversation's options", turn)
+	}
+}
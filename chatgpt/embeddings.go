@@ -0,0 +1,111 @@
+package chatgpt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jkrebs-tr/goUtils/http"
+)
+
+// Limits of a single embeddings request; EmbedWith splits larger inputs into batches
+const (
+	maxEmbeddingInputs = 2048
+	maxEmbeddingTokens = 300000
+)
+
+// Embedder is a provider that turns text into embedding vectors. OpenAI and
+// AzureOpenAI implement it.
+type Embedder interface {
+	// Embed sends a single embeddings request. Use EmbedWith for inputs of any size.
+	Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error)
+}
+
+func (o *OpenAI) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	return o.endpoint().embed(ctx, req)
+}
+
+func (a *AzureOpenAI) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	endpoint, err := a.endpoint(ctx)
+	if err != nil {
+		return EmbeddingResponse{}, err
+	}
+	return endpoint.embed(ctx, req)
+}
+
+func (e apiEndpoint) embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	var response EmbeddingResponse
+	err := http.DoCtx(ctx, e.client, "POST", e.root+"/embeddings", &response, req, e.params, e.headers)
+	if err != nil {
+		return EmbeddingResponse{}, err
+	}
+	return response, nil
+}
+
+// Embed returns an OpenAI embedding for each input, in input order. Inputs are sent in
+// batches that stay within the per-request limits (2048 inputs, 300k tokens).
+//
+// Parameters:
+//   - ctx: Context for the requests
+//   - model: The embedding model you want to use (text-embedding-3-small)
+//   - inputs: The texts to embed, none of them empty
+//   - key: The openAPI key to use in the requests
+//
+// Returns:
+//   - [][]float32: One vector per input
+//   - Usage: The tokens used across all batches
+//   - Error: Any errors that occur during execution
+//
+// Example Usage:
+//
+//	vectors, _, err := Embed(ctx, "text-embedding-3-small", []string{"refund not received", "cannot log in"}, os.Getenv("OPENAI_API_KEY"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(len(vectors[0])) // 1536
+func Embed(ctx context.Context, model string, inputs []string, key string) ([][]float32, Usage, error) {
+	return EmbedWith(ctx, &OpenAI{APIKey: key}, model, inputs)
+}
+
+// EmbedWith is like Embed but sends the batches to any Embedder, e.g. AzureOpenAI or
+// an OpenAI-compatible server.
+func EmbedWith(ctx context.Context, e Embedder, model string, inputs []string) ([][]float32, Usage, error) {
+	vectors := make([][]float32, len(inputs))
+	var usage Usage
+
+	start := 0
+	for start < len(inputs) {
+		end, tokens := start, 0
+		for end < len(inputs) && end-start < maxEmbeddingInputs {
+			if inputs[end] == "" {
+				return nil, usage, fmt.Errorf("Error Embedding Inputs: input %d is empty", end)
+			}
+			n := countTextTokens(model, inputs[end])
+			if end > start && tokens+n > maxEmbeddingTokens {
+				break
+			}
+			tokens += n
+			end++
+		}
+
+		resp, err := e.Embed(ctx, EmbeddingRequest{Model: model, Input: inputs[start:end]})
+		if err != nil {
+			return nil, usage, fmt.Errorf("Error Embedding Inputs %d-%d: %w", start, end-1, err)
+		}
+		if len(resp.Data) != end-start {
+			return nil, usage, fmt.Errorf("Error Embedding Inputs %d-%d: got %d embeddings for %d inputs", start, end-1, len(resp.Data), end-start)
+		}
+		for i, data := range resp.Data {
+			index := data.Index
+			if index < 0 || index >= end-start {
+				index = i
+			}
+			vectors[start+index] = data.Embedding
+		}
+		if resp.Usage != nil {
+			usage.PromptTokens += resp.Usage.PromptTokens
+			usage.TotalTokens += resp.Usage.TotalTokens
+		}
+		start = end
+	}
+	return vectors, usage, nil
+}
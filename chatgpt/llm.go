@@ -0,0 +1,101 @@
+package chatgpt
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+
+	"github.com/jkrebs-tr/goUtils/http"
+)
+
+// LLM is a chat model provider. Requests and responses use the OpenAI chat completions
+// shape (ChatRequest, Response, Chunk) whatever the provider, so callers can swap
+// providers without changing their code.
+type LLM interface {
+	// Chat sends a request and returns the complete response.
+	Chat(ctx context.Context, req ChatRequest) (Response, error)
+	// Stream sends a request with streaming enabled and yields its chunks, ending with
+	// one carrying the Usage.
+	Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error]
+}
+
+// ProviderConfig selects and configures an LLM, e.g. from a config file or environment.
+type ProviderConfig struct {
+	Provider   string `json:"provider"`              // "openai", "azure", "anthropic", or "openai-compatible" (also "ollama", "vllm")
+	Model      string `json:"model,omitempty"`       // used for requests that don't name a model
+	APIKey     string `json:"api_key,omitempty"`     // defaults to OPENAI_API_KEY, AZURE_OPENAI_API_KEY or ANTHROPIC_API_KEY
+	BaseURL    string `json:"base_url,omitempty"`    // API root, or the resource endpoint for Azure
+	Deployment string `json:"deployment,omitempty"`  // Azure deployment name
+	APIVersion string `json:"api_version,omitempty"` // Azure api-version or anthropic-version
+	MaxTokens  int    `json:"max_tokens,omitempty"`  // Anthropic's required max_tokens, defaults to 4096
+}
+
+// NewLLM creates the LLM described by cfg.
+//
+// Parameters:
+//   - cfg: The provider and its settings
+//
+// Returns:
+//   - LLM: The provider's adapter
+//   - Error: If the provider is unknown or a required setting is missing
+//
+// Example Usage:
+//
+//	// config.json: {"provider": "ollama", "base_url": "http://localhost:11434/v1", "model": "llama3.1"}
+//	var cfg ProviderConfig
+//	json.Unmarshal(configJSON, &cfg)
+//
+//	llm, err := NewLLM(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	resp, err := llm.Chat(ctx, ChatRequest{Messages: messages})
+func NewLLM(cfg ProviderConfig) (LLM, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "openai", "":
+		return &OpenAI{APIKey: keyOrEnv(cfg.APIKey, "OPENAI_API_KEY"), BaseURL: cfg.BaseURL, Model: cfg.Model}, nil
+	case "openai-compatible", "ollama", "vllm":
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("Error Creating LLM: %s needs a base_url", cfg.Provider)
+		}
+		return &OpenAI{APIKey: cfg.APIKey, BaseURL: cfg.BaseURL, Model: cfg.Model}, nil
+	case "azure", "azure-openai":
+		if cfg.BaseURL == "" || cfg.Deployment == "" {
+			return nil, fmt.Errorf("Error Creating LLM: azure needs a base_url and deployment")
+		}
+		return &AzureOpenAI{
+			Endpoint:   cfg.BaseURL,
+			Deployment: cfg.Deployment,
+			APIVersion: cfg.APIVersion,
+			APIKey:     keyOrEnv(cfg.APIKey, "AZURE_OPENAI_API_KEY"),
+			Model:      cfg.Model,
+		}, nil
+	case "anthropic", "claude":
+		return &Anthropic{
+			APIKey:    keyOrEnv(cfg.APIKey, "ANTHROPIC_API_KEY"),
+			BaseURL:   cfg.BaseURL,
+			Version:   cfg.APIVersion,
+			MaxTokens: cfg.MaxTokens,
+			Model:     cfg.Model,
+		}, nil
+	}
+	return nil, fmt.Errorf("Error Creating LLM: unknown provider %q", cfg.Provider)
+}
+
+func keyOrEnv(key string, env string) string {
+	if key != "" {
+		return key
+	}
+	return os.Getenv(env)
+}
+
+// clientOrDefault returns c, or http.DefaultClient at the time of the call so
+// swapping the default client (e.g. for a Replayer in tests) applies to providers too
+func clientOrDefault(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,162 @@
+package chatgpt
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/jkrebs-tr/goUtils/http"
+)
+
+// openAIBaseURL is the root of the OpenAI API
+const openAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI is the LLM for the OpenAI API and for servers that implement its chat
+// completions endpoint, such as Ollama (http://localhost:11434/v1) or vLLM
+// (http://localhost:8000/v1).
+type OpenAI struct {
+	APIKey       string       // sent as a bearer token, may be empty for local servers
+	BaseURL      string       // defaults to https://api.openai.com/v1
+	Organization string       // optional OpenAI-Organization header
+	Model        string       // used for requests that don't name a model
+	Client       *http.Client // defaults to http.DefaultClient
+}
+
+func (o *OpenAI) Chat(ctx context.Context, req ChatRequest) (Response, error) {
+	return o.endpoint().chat(ctx, withModel(req, o.Model))
+}
+
+func (o *OpenAI) Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	return o.endpoint().stream(ctx, withModel(req, o.Model))
+}
+
+func (o *OpenAI) endpoint() apiEndpoint {
+	baseURL := o.BaseURL
+	if baseURL == "" {
+		baseURL = openAIBaseURL
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	if o.APIKey != "" {
+		headers["Authorization"] = fmt.Sprintf("Bearer %s", o.APIKey)
+	}
+	if o.Organization != "" {
+		headers["OpenAI-Organization"] = o.Organization
+	}
+	return apiEndpoint{
+		root:    strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  clientOrDefault(o.Client),
+	}
+}
+
+// AzureOpenAI is the LLM for Azure OpenAI deployments. It authenticates with APIKey,
+// or with a Microsoft Entra ID token from TokenSource when APIKey is empty.
+type AzureOpenAI struct {
+	Endpoint    string           // resource endpoint, e.g. https://my-resource.openai.azure.com
+	Deployment  string           // deployment name, which selects the model
+	APIVersion  string           // defaults to 2024-10-21
+	APIKey      string           // sent in the api-key header
+	TokenSource http.TokenSource // used instead of APIKey, e.g. http.ClientCredentials with the cognitiveservices scope
+	Model       string           // sent as the request's model, ignored by Azure
+	Client      *http.Client     // defaults to http.DefaultClient
+}
+
+func (a *AzureOpenAI) Chat(ctx context.Context, req ChatRequest) (Response, error) {
+	endpoint, err := a.endpoint(ctx)
+	if err != nil {
+		return Response{}, err
+	}
+	return endpoint.chat(ctx, withModel(req, a.Model))
+}
+
+func (a *AzureOpenAI) Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	endpoint, err := a.endpoint(ctx)
+	if err != nil {
+		return func(yield func(Chunk, error) bool) { yield(Chunk{}, err) }
+	}
+	return endpoint.stream(ctx, withModel(req, a.Model))
+}
+
+func (a *AzureOpenAI) endpoint(ctx context.Context) (apiEndpoint, error) {
+	version := a.APIVersion
+	if version == "" {
+		version = "2024-10-21"
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	switch {
+	case a.APIKey != "":
+		headers["api-key"] = a.APIKey
+	case a.TokenSource != nil:
+		token, err := a.TokenSource.Token(ctx)
+		if err != nil {
+			return apiEndpoint{}, fmt.Errorf("Error Fetching Azure Token: %w", err)
+		}
+		headers["Authorization"] = "Bearer " + token.AccessToken
+	}
+	return apiEndpoint{
+		root:    fmt.Sprintf("%s/openai/deployments/%s", strings.TrimSuffix(a.Endpoint, "/"), a.Deployment),
+		params:  map[string]string{"api-version": version},
+		headers: headers,
+		client:  clientOrDefault(a.Client),
+	}, nil
+}
+
+// apiEndpoint sends requests to an OpenAI style API rooted at root
+type apiEndpoint struct {
+	root    string
+	params  map[string]string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (e apiEndpoint) chat(ctx context.Context, req ChatRequest) (Response, error) {
+	req.Stream = false
+	req.StreamOptions = nil
+
+	var response Response
+	err := http.DoCtx(ctx, e.client, "POST", e.root+"/chat/completions", &response, req, e.params, e.headers)
+	if err != nil {
+		return Response{}, err
+	}
+	return response, nil
+}
+
+func (e apiEndpoint) stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	req.Stream = true
+	if req.StreamOptions == nil {
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	return func(yield func(Chunk, error) bool) {
+		events := http.TypedEvents[Chunk](ctx, e.client, http.SSERequest{
+			Method:   "POST",
+			URL:      e.root + "/chat/completions",
+			Body:     req,
+			Params:   e.params,
+			Headers:  e.headers,
+			DoneData: "[DONE]",
+		})
+		for event, err := range events {
+			if err != nil {
+				yield(Chunk{}, err)
+				return
+			}
+			if event.Value.Error != nil {
+				yield(Chunk{}, event.Value.Error)
+				return
+			}
+			if !yield(event.Value, nil) {
+				return
+			}
+		}
+	}
+}
+
+// withModel fills in the request's model from the provider's default
+func withModel(req ChatRequest, model string) ChatRequest {
+	if req.Model == "" {
+		req.Model = model
+	}
+	return req
+}
@@ -0,0 +1,177 @@
+package chatgpt
+
+// RequestOption configures a single chat request sent with Chat, ChatStream, etc.
+type RequestOption func(*requestConfig)
+
+// requestConfig holds the request body and the provider collected from RequestOptions
+type requestConfig struct {
+	req     ChatRequest
+	key     string
+	llm     LLM
+	tracker *UsageTracker
+}
+
+func newRequestConfig(req ChatRequest, key string, opts []RequestOption) *requestConfig {
+	cfg := &requestConfig{req: req, key: key}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// provider returns the LLM set with WithLLM, or OpenAI with the configured key
+// (OPENAI_API_KEY when none was given), tracked when WithUsageTracker is set
+func (cfg *requestConfig) provider() LLM {
+	llm := cfg.llm
+	if llm == nil {
+		llm = &OpenAI{APIKey: keyOrEnv(cfg.key, "OPENAI_API_KEY")}
+	}
+	if cfg.tracker != nil {
+		llm = cfg.tracker.Track(llm)
+	}
+	return llm
+}
+
+// WithKey sets the OpenAI API key, replacing the OPENAI_API_KEY default.
+func WithKey(key string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.key = key
+	}
+}
+
+// WithLLM sends the request to llm instead of OpenAI, e.g. one created with NewLLM.
+func WithLLM(llm LLM) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.llm = llm
+	}
+}
+
+// WithModel sets the model, which may be left out when the LLM has a default.
+func WithModel(model string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Model = model
+	}
+}
+
+// WithTemperature sets the sampling temperature (0 = deterministic | 2 = random).
+func WithTemperature(tmp float32) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Temperature = tmp
+	}
+}
+
+// WithTopP sets nucleus sampling, considering only the tokens within the top p probability mass.
+func WithTopP(p float32) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.TopP = &p
+	}
+}
+
+// WithMaxTokens limits the number of tokens generated. Replies cut off by the limit
+// have a FinishReason of "length", see Choice.Truncated.
+func WithMaxTokens(n int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.MaxTokens = n
+	}
+}
+
+// WithMaxCompletionTokens limits the tokens generated, reasoning tokens included. Reasoning
+// models (o1, o3, ...) take this instead of WithMaxTokens.
+func WithMaxCompletionTokens(n int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.MaxCompletionTokens = n
+	}
+}
+
+// WithN asks for n choices instead of one.
+func WithN(n int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.N = n
+	}
+}
+
+// WithStop sets up to 4 sequences that end generation when produced.
+func WithStop(sequences ...string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Stop = sequences
+	}
+}
+
+// WithSeed makes sampling repeatable on a best-effort basis.
+func WithSeed(seed int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Seed = &seed
+	}
+}
+
+// WithPresencePenalty penalizes tokens that have appeared at all (-2 to 2).
+func WithPresencePenalty(penalty float32) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.PresencePenalty = &penalty
+	}
+}
+
+// WithFrequencyPenalty penalizes tokens by how often they have appeared (-2 to 2).
+func WithFrequencyPenalty(penalty float32) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.FrequencyPenalty = &penalty
+	}
+}
+
+// WithLogitBias adjusts the likelihood of tokens, keyed by token ID, from -100 (ban) to 100 (force).
+func WithLogitBias(bias map[string]int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.LogitBias = bias
+	}
+}
+
+// WithUser identifies the end user to the provider for abuse monitoring.
+func WithUser(user string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.User = user
+	}
+}
+
+// WithLogprobs returns the log probability of every generated token in Choice.Logprobs,
+// along with the top most likely alternatives (0 to 20) for each.
+func WithLogprobs(top int) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Logprobs = true
+		cfg.req.TopLogprobs = top
+	}
+}
+
+// WithReasoningEffort sets how long reasoning models think ("low", "medium" or "high").
+func WithReasoningEffort(effort string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.ReasoningEffort = effort
+	}
+}
+
+// WithTools offers tools the model may call, see ToolRegistry.Tools.
+func WithTools(tools ...Tool) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.Tools = tools
+	}
+}
+
+// WithToolChoice sets "auto", "none", "required" or ToolChoiceFunction(name).
+func WithToolChoice(choice any) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.ToolChoice = choice
+	}
+}
+
+// WithParallelToolCalls allows or prevents several tool calls in one reply.
+func WithParallelToolCalls(parallel bool) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.ParallelToolCalls = &parallel
+	}
+}
+
+// WithResponseFormat constrains the reply's format, see SendStructured for typed replies.
+func WithResponseFormat(format *ResponseFormat) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.req.ResponseFormat = format
+	}
+}
@@ -0,0 +1,141 @@
+package chatgpt
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchema is the subset of JSON Schema used to describe tool parameters and
+// structured outputs.
+type JSONSchema struct {
+	Type                 any                    `json:"type,omitempty"` // a type name, or a list of them such as ["string", "null"]
+	Description          string                 `json:"description,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false, or the schema of map values
+}
+
+// SchemaFor derives a JSON Schema from the Go type T, which is usually a struct.
+//
+// Field names come from the json tag, fields without omitempty are required, and
+// two extra tags are understood:
+//   - description:"..." documents the field for the model
+//   - enum:"a,b,c" restricts the field to the listed values
+//
+// Example Usage:
+//
+//	type WeatherArgs struct {
+//		City string `json:"city" description:"City name, e.g. Berlin"`
+//		Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+//	schema := SchemaFor[WeatherArgs]()
+func SchemaFor[T any]() *JSONSchema {
+	return schemaOf(reflect.TypeFor[T](), map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+var rawMessageType = reflect.TypeFor[json.RawMessage]()
+
+// schemaOf builds the schema of t. seen holds the structs being built, so recursive
+// types end in an unconstrained schema instead of looping.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &JSONSchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string
+			return &JSONSchema{Type: "string"}
+		}
+		return &JSONSchema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &JSONSchema{}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}, AdditionalProperties: false}
+		addFields(schema, t, seen)
+		return schema
+	}
+	// interfaces and anything else accept any value
+	return &JSONSchema{}
+}
+
+// addFields adds the exported fields of struct type t to schema, flattening embedded structs
+func addFields(schema *JSONSchema, t reflect.Type, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addFields(schema, fieldType, seen)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := schemaOf(field.Type, seen)
+		prop.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, value := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, enumValue(prop.Type, strings.TrimSpace(value)))
+			}
+		}
+
+		schema.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// enumValue converts an enum tag value to the JSON type of the field
+func enumValue(schemaType any, value string) any {
+	if schemaType == "integer" || schemaType == "number" || schemaType == "boolean" {
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
@@ -0,0 +1,169 @@
+package chatgpt
+
+import (
+	"context"
+	"iter"
+)
+
+// StreamRequest sends a request to ChatGPT with streaming enabled, calling onDelta with
+// every piece of content as it arrives, and returns the assembled Response once the
+// stream ends, including its Usage. Cancelling ctx stops the stream.
+//
+// Parameters:
+//   - ctx: Context for the request, cancel it to stop generation
+//   - model: The GPT model you want to use (gpt-4)
+//   - messages: The messages/context to send to gpt
+//   - tmp: The temperature for gpt (0 = detreministic | 1 = random)
+//   - key: The openAPI key to use in the request
+//   - onDelta: Called with each content delta, may be nil
+//   - opts: Further request options, e.g. WithMaxTokens or WithLLM
+//
+// Returns:
+//   - Response: The complete response, as SendRequest would have returned it
+//   - Error: Any errors that occur during execution
+//
+// Example Usage:
+//
+//	resp, err := StreamRequest(ctx, "gpt-4", messages, 0.7, os.Getenv("OPENAI_API_KEY"), func(delta string) {
+//		fmt.Print(delta)
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Printf("\n(%d tokens)\n", resp.Usage.TotalTokens)
+func StreamRequest(ctx context.Context, model string, messages []Message, tmp float32, key string, onDelta func(delta string), opts ...RequestOption) (Response, error) {
+	return streamResponse(streamChunks(ctx, ChatRequest{Model: model, Messages: messages, Temperature: tmp}, key, opts), onDelta)
+}
+
+// ChatStream is Chat with streaming enabled: onDelta is called with every piece of
+// content as it arrives and the assembled Response is returned once the stream ends.
+//
+// Example Usage:
+//
+//	resp, err := ChatStream(ctx, messages, func(delta string) {
+//		fmt.Print(delta)
+//	}, WithModel("gpt-4o"), WithMaxTokens(500))
+func ChatStream(ctx context.Context, messages []Message, onDelta func(delta string), opts ...RequestOption) (Response, error) {
+	return streamResponse(streamChunks(ctx, ChatRequest{Messages: messages, Temperature: 1}, "", opts), onDelta)
+}
+
+// streamResponse assembles chunks into a Response, passing content deltas to onDelta
+func streamResponse(chunks iter.Seq2[Chunk, error], onDelta func(delta string)) (Response, error) {
+	var acc streamAccumulator
+	for chunk, err := range chunks {
+		if err != nil {
+			return Response{}, err
+		}
+		acc.add(chunk)
+		if onDelta != nil {
+			for _, choice := range chunk.Choices {
+				if choice.Index == 0 && choice.Delta.Content != "" {
+					onDelta(choice.Delta.Content)
+				}
+			}
+		}
+	}
+	return acc.response(), nil
+}
+
+// StreamDeltas is like StreamRequest but yields the content deltas of the first choice
+// through an iterator. Use StreamRequest when the final Response or Usage is needed.
+//
+// Example Usage:
+//
+//	for delta, err := range StreamDeltas(ctx, "gpt-4", messages, 0.7, os.Getenv("OPENAI_API_KEY")) {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Print(delta)
+//	}
+func StreamDeltas(ctx context.Context, model string, messages []Message, tmp float32, key string, opts ...RequestOption) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for chunk, err := range StreamChunks(ctx, model, messages, tmp, key, opts...) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			for _, choice := range chunk.Choices {
+				if choice.Index == 0 && choice.Delta.Content != "" && !yield(choice.Delta.Content, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// StreamChunks yields the raw chunks of a streamed completion, ending with the
+// usage-only chunk. Errors the API reports mid-stream are yielded as *APIError.
+func StreamChunks(ctx context.Context, model string, messages []Message, tmp float32, key string, opts ...RequestOption) iter.Seq2[Chunk, error] {
+	return streamChunks(ctx, ChatRequest{Model: model, Messages: messages, Temperature: tmp}, key, opts)
+}
+
+// streamChunks applies opts to body and streams it from the configured provider
+func streamChunks(ctx context.Context, body ChatRequest, key string, opts []RequestOption) iter.Seq2[Chunk, error] {
+	cfg := newRequestConfig(body, key, opts)
+	cfg.req.Stream = true
+	if cfg.req.StreamOptions == nil {
+		cfg.req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return cfg.provider().Stream(ctx, cfg.req)
+}
+
+// streamAccumulator assembles streamed chunks into a Response
+type streamAccumulator struct {
+	resp Response
+}
+
+func (a *streamAccumulator) add(chunk Chunk) {
+	if a.resp.ID == "" {
+		a.resp.ID = chunk.ID
+		a.resp.Object = "chat.completion"
+		a.resp.Created = chunk.Created
+		a.resp.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = chunk.Usage
+	}
+
+	for _, delta := range chunk.Choices {
+		for len(a.resp.Choices) <= delta.Index {
+			a.resp.Choices = append(a.resp.Choices, Choice{Index: len(a.resp.Choices), Message: Message{Role: "assistant"}})
+		}
+		choice := &a.resp.Choices[delta.Index]
+		if delta.FinishReason != "" {
+			choice.FinishReason = delta.FinishReason
+		}
+		if delta.Logprobs != nil {
+			if choice.Logprobs == nil {
+				choice.Logprobs = &Logprobs{}
+			}
+			choice.Logprobs.Content = append(choice.Logprobs.Content, delta.Logprobs.Content...)
+			choice.Logprobs.Refusal = append(choice.Logprobs.Refusal, delta.Logprobs.Refusal...)
+		}
+
+		msg := &choice.Message
+		if delta.Delta.Role != "" {
+			msg.Role = delta.Delta.Role
+		}
+		msg.Content += delta.Delta.Content
+		msg.Refusal += delta.Delta.Refusal
+
+		for _, call := range delta.Delta.ToolCalls {
+			for len(msg.ToolCalls) <= call.Index {
+				msg.ToolCalls = append(msg.ToolCalls, ToolCall{Type: "function"})
+			}
+			tc := &msg.ToolCalls[call.Index]
+			if call.ID != "" {
+				tc.ID = call.ID
+			}
+			if call.Function.Name != "" {
+				tc.Function.Name = call.Function.Name
+			}
+			tc.Function.Arguments += call.Function.Arguments
+		}
+	}
+}
+
+func (a *streamAccumulator) response() Response {
+	return a.resp
+}
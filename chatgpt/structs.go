@@ -1,9 +1,125 @@
 package chatgpt
 
+import "fmt"
+
+// ChatRequest is the body of a chat completions request. Optional parameters are left
+// out of the request when unset; see the RequestOption functions to set them.
 type ChatRequest struct {
-    Model       string    `json:"model"`
-    Messages    []Message `json:"messages"`
-    Temperature float32   `json:"temperature"`
+	Model               string          `json:"model"`
+	Messages            []Message       `json:"messages"`
+	Temperature         float32         `json:"temperature"`
+	TopP                *float32        `json:"top_p,omitempty"`
+	MaxTokens           int             `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"` // replaces MaxTokens for reasoning models
+	N                   int             `json:"n,omitempty"`                     // number of choices to generate
+	Stop                []string        `json:"stop,omitempty"`
+	Seed                *int            `json:"seed,omitempty"`
+	PresencePenalty     *float32        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float32        `json:"frequency_penalty,omitempty"`
+	LogitBias           map[string]int  `json:"logit_bias,omitempty"` // token ID -> bias from -100 to 100
+	User                string          `json:"user,omitempty"`
+	Logprobs            bool            `json:"logprobs,omitempty"`
+	TopLogprobs         int             `json:"top_logprobs,omitempty"` // alternatives per token, needs Logprobs
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"`
+	Stream              bool            `json:"stream,omitempty"`
+	StreamOptions       *StreamOptions  `json:"stream_options,omitempty"`
+	Tools               []Tool          `json:"tools,omitempty"`
+	ToolChoice          any             `json:"tool_choice,omitempty"` // "auto", "none", "required" or ToolChoiceFunction(name)
+	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat      *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat constrains the format of the model's reply.
+type ResponseFormat struct {
+	Type       string            `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat is the schema a "json_schema" ResponseFormat must follow.
+type JSONSchemaFormat struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Schema      *JSONSchema `json:"schema"`
+	Strict      bool        `json:"strict,omitempty"`
+}
+
+// Tool describes a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function FunctionSpec `json:"function"`
+}
+
+// FunctionSpec is the name, description and JSON Schema parameters of a callable function.
+type FunctionSpec struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  *JSONSchema `json:"parameters,omitempty"`
+	Strict      bool        `json:"strict,omitempty"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	Index    int          `json:"index,omitempty"` // position of the call, only set in streamed deltas
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall is the function name and JSON encoded arguments of a ToolCall.
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolChoiceFunction forces the model to call the named function.
+func ToolChoiceFunction(name string) any {
+	return map[string]any{"type": "function", "function": map[string]string{"name": name}}
+}
+
+// StreamOptions configures a streamed completion.
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // send a final chunk with the request's Usage
+}
+
+// Chunk is one server-sent event of a streamed completion.
+type Chunk struct {
+	ID      string        `json:"id"`
+	Object  string        `json:"object"`
+	Created int64         `json:"created"`
+	Model   string        `json:"model"`
+	Choices []ChunkChoice `json:"choices"`
+	Usage   *Usage        `json:"usage,omitempty"`
+	Error   *APIError     `json:"error,omitempty"`
+}
+
+// ChunkChoice carries the delta for one choice in a Chunk.
+type ChunkChoice struct {
+	Index        int       `json:"index"`
+	Delta        Delta     `json:"delta"`
+	FinishReason string    `json:"finish_reason,omitempty"`
+	Logprobs     *Logprobs `json:"logprobs,omitempty"`
+}
+
+// Delta is the part of a message added by a Chunk.
+type Delta struct {
+	Role      string     `json:"role,omitempty"`
+	Content   string     `json:"content,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Refusal   string     `json:"refusal,omitempty"`
+}
+
+// APIError is an error reported by the API inside a response body.
+type APIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    any    `json:"code,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("OpenAI API error (%s): %s", e.Type, e.Message)
+	}
+	return "OpenAI API error: " + e.Message
 }
 
 type Response struct {
@@ -16,16 +132,75 @@ type Response struct {
 }
 
 type Choice struct {
-	Message Message `json:"message"`
+	Index        int       `json:"index"`
+	Message      Message   `json:"message"`
+	FinishReason string    `json:"finish_reason"` // "stop", "length", "tool_calls" or "content_filter"
+	Logprobs     *Logprobs `json:"logprobs,omitempty"`
+}
+
+// Truncated reports whether the reply was cut off by the token limit.
+func (c Choice) Truncated() bool {
+	return c.FinishReason == "length"
+}
+
+// Logprobs holds the log probabilities of a choice's tokens, see WithLogprobs.
+type Logprobs struct {
+	Content []TokenLogprob `json:"content"`
+	Refusal []TokenLogprob `json:"refusal,omitempty"`
+}
+
+type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	Bytes       []int        `json:"bytes,omitempty"`
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"` // the most likely alternatives
 }
 
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
+}
+
+// Message is one message of a chat. Plain text goes in Content; for images, audio or
+// files set Parts instead, which is sent as an array of content parts in place of
+// Content. Messages without Parts encode exactly as before.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	Parts      []ContentPart `json:"-"`                      // multimodal content, see ImagePart, ImageFromFile, etc.
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`   // calls requested by an assistant message
+	ToolCallID string        `json:"tool_call_id,omitempty"` // the call a "tool" message answers
+	Refusal    string        `json:"refusal,omitempty"`      // set instead of Content when the model declines a structured output
 }
 
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"` // prompt tokens served from the prompt cache, billed at a discount
+}
+
+// EmbeddingRequest is the body of an embeddings request
+type EmbeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"` // shortens text-embedding-3 vectors
+}
+
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  *Usage      `json:"usage,omitempty"`
+}
+
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"` // position of the input it embeds
+	Embedding []float32 `json:"embedding"`
 }
@@ -0,0 +1,262 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// structuredAttempts is how many times SendStructured asks before giving up
+const structuredAttempts = 3
+
+// ErrRefusal is returned by SendStructured when the model declines to answer.
+var ErrRefusal = errors.New("model refused the request")
+
+// ErrUnsupportedSchema is returned by SendStructured, before anything is sent, when T
+// can't be described in strict mode: maps, interface{} or json.RawMessage fields,
+// recursive types and anything but a struct at the root.
+var ErrUnsupportedSchema = errors.New("type is not supported by strict structured outputs")
+
+// SendStructured asks ChatGPT for a reply matching T and decodes it. The JSON Schema
+// of T (see SchemaFor) is sent as a strict "json_schema" response format, so optional
+// fields become nullable and every field is present in the reply. T must be a struct whose
+// fields all have a fixed shape, see ErrUnsupportedSchema. If the reply doesn't
+// validate against the schema, the error is sent back to the model and it is asked again,
+// up to 3 times in all.
+//
+// Parameters:
+//   - ctx: Context for the requests
+//   - model: A GPT model supporting structured outputs (gpt-4o)
+//   - messages: The messages/context to send to gpt
+//   - tmp: The temperature for gpt (0 = detreministic | 1 = random)
+//   - key: The openAPI key to use in the requests
+//   - opts: Further request options, e.g. WithLLM or WithSeed
+//
+// Returns:
+//   - T: The decoded reply
+//   - Response: The last response received
+//   - Error: Any errors that occur during execution, ErrRefusal if the model declined,
+//     ErrUnsupportedSchema if T can't be sent as a strict schema
+//
+// Example Usage:
+//
+//	type Invoice struct {
+//		Number   string  `json:"number"`
+//		Total    float64 `json:"total" description:"Grand total including tax"`
+//		Currency string  `json:"currency" enum:"EUR,USD,GBP"`
+//		DueDate  string  `json:"due_date,omitempty" description:"YYYY-MM-DD"`
+//	}
+//
+//	messages := []Message{
+//		{Role: "system", Content: "Extract the invoice details."},
+//		{Role: "user", Content: invoiceText},
+//	}
+//	invoice, _, err := SendStructured[Invoice](ctx, "gpt-4o", messages, 0, os.Getenv("OPENAI_API_KEY"))
+func SendStructured[T any](ctx context.Context, model string, messages []Message, tmp float32, key string, opts ...RequestOption) (T, Response, error) {
+	var zero T
+	schema, err := strictSchema(SchemaFor[T](), "$")
+	if err != nil {
+		return zero, Response{}, fmt.Errorf("Error Building Structured Schema for %s: %w", reflect.TypeFor[T](), err)
+	}
+	if schema.Type != "object" {
+		return zero, Response{}, fmt.Errorf("Error Building Structured Schema for %s: %w: the root must be a struct", reflect.TypeFor[T](), ErrUnsupportedSchema)
+	}
+	body := ChatRequest{
+		Model:       model,
+		Messages:    append([]Message(nil), messages...),
+		Temperature: tmp,
+		ResponseFormat: &ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &JSONSchemaFormat{
+				Name:   schemaName(reflect.TypeFor[T]()),
+				Schema: schema,
+				Strict: true,
+			},
+		},
+	}
+
+	var lastResp Response
+	var lastErr error
+	for attempt := 1; attempt <= structuredAttempts; attempt++ {
+		resp, err := sendChat(ctx, body, key, opts)
+		if err != nil {
+			return zero, resp, err
+		}
+		lastResp = resp
+		if len(resp.Choices) == 0 {
+			return zero, resp, fmt.Errorf("Error Sending Structured Request: response has no choices")
+		}
+
+		reply := resp.Choices[0].Message
+		if reply.Refusal != "" {
+			return zero, resp, fmt.Errorf("%w: %s", ErrRefusal, reply.Refusal)
+		}
+
+		var result T
+		lastErr = decodeStructured(reply.Content, schema, &result)
+		if lastErr == nil {
+			return result, resp, nil
+		}
+
+		body.Messages = append(body.Messages, reply, Message{
+			Role:    "user",
+			Content: fmt.Sprintf("Your reply is not valid: %v. Reply again with only the corrected JSON matching the schema.", lastErr),
+		})
+	}
+	return zero, lastResp, fmt.Errorf("Error Decoding Structured Reply after %d attempts: %w", structuredAttempts, lastErr)
+}
+
+// decodeStructured validates content against schema and unmarshals it into out
+func decodeStructured(content string, schema *JSONSchema, out any) error {
+	var doc any
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := validateSchema(schema, doc, "$"); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(content), out); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// validateSchema checks a decoded JSON value against the parts of schema SchemaFor produces
+func validateSchema(schema *JSONSchema, value any, path string) error {
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schemaAllows(schema.Type, "null") || schema.Type == nil {
+			return nil
+		}
+		return fmt.Errorf("%s must not be null", path)
+	}
+
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, value) {
+		return fmt.Errorf("%s must be one of %v, got %v", path, schema.Enum, value)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if !schemaAllows(schema.Type, "object") {
+			return fmt.Errorf("%s must be %v, got an object", path, schema.Type)
+		}
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		for name, field := range v {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if additional, ok := schema.AdditionalProperties.(*JSONSchema); ok {
+					prop = additional
+				} else if schema.AdditionalProperties == false {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+			}
+			if err := validateSchema(prop, field, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if !schemaAllows(schema.Type, "array") {
+			return fmt.Errorf("%s must be %v, got an array", path, schema.Type)
+		}
+		for i, item := range v {
+			if err := validateSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case string:
+		if !schemaAllows(schema.Type, "string") {
+			return fmt.Errorf("%s must be %v, got a string", path, schema.Type)
+		}
+	case bool:
+		if !schemaAllows(schema.Type, "boolean") {
+			return fmt.Errorf("%s must be %v, got a boolean", path, schema.Type)
+		}
+	case float64:
+		isInt := v == float64(int64(v))
+		if !schemaAllows(schema.Type, "number") && !(isInt && schemaAllows(schema.Type, "integer")) {
+			return fmt.Errorf("%s must be %v, got %v", path, schema.Type, v)
+		}
+	}
+	return nil
+}
+
+// schemaAllows reports whether a schema type (a name, a list of names or nil for any) includes name
+func schemaAllows(schemaType any, name string) bool {
+	switch t := schemaType.(type) {
+	case nil:
+		return true
+	case string:
+		return t == name
+	case []any:
+		return slices.Contains(t, any(name))
+	}
+	return false
+}
+
+// strictSchema adapts a schema to OpenAI's strict mode: every property is required
+// and properties that were optional become nullable instead. Strict mode has no way to
+// express maps or values of any type, so schemas with additional properties or without a
+// type (interface{}, json.RawMessage and recursive types, see schemaOf) fail with
+// ErrUnsupportedSchema. path names the schema in errors.
+func strictSchema(schema *JSONSchema, path string) (*JSONSchema, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	if schema.Type == nil {
+		return nil, fmt.Errorf("%w: %s has no fixed type (interface{}, json.RawMessage or a recursive type)", ErrUnsupportedSchema, path)
+	}
+	if _, ok := schema.AdditionalProperties.(*JSONSchema); ok {
+		return nil, fmt.Errorf("%w: %s is a map, use a struct or a slice of key/value structs", ErrUnsupportedSchema, path)
+	}
+
+	strict := *schema
+	items, err := strictSchema(schema.Items, path+"[]")
+	if err != nil {
+		return nil, err
+	}
+	strict.Items = items
+
+	if schema.Properties != nil {
+		strict.Properties = make(map[string]*JSONSchema, len(schema.Properties))
+		strict.Required = make([]string, 0, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			prop, err := strictSchema(prop, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(schema.Required, name) {
+				prop.Type = []any{prop.Type, "null"}
+				if len(prop.Enum) > 0 {
+					prop.Enum = append(prop.Enum, nil)
+				}
+			}
+			strict.Properties[name] = prop
+			strict.Required = append(strict.Required, name)
+		}
+		slices.Sort(strict.Required)
+	}
+	return &strict, nil
+}
+
+var invalidSchemaName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// schemaName turns a Go type name into a valid response format name
+func schemaName(t reflect.Type) string {
+	name := invalidSchemaName.ReplaceAllString(t.Name(), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "response"
+	}
+	return name
+}
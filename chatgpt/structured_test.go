@@ -0,0 +1,105 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type structuredInvoice struct {
+	Number   string             `json:"number"`
+	Currency string             `json:"currency,omitempty" enum:"EUR,USD"`
+	Lines    []structuredLine   `json:"lines"`
+	Customer *structuredAddress `json:"customer,omitempty"`
+}
+
+type structuredLine struct {
+	Item  string  `json:"item"`
+	Total float64 `json:"total"`
+}
+
+type structuredAddress struct {
+	City string `json:"city"`
+}
+
+type structuredNode struct {
+	Name     string           `json:"name"`
+	Children []structuredNode `json:"children"`
+}
+
+func TestSendStructured(t *testing.T) {
+	llm := &fakeLLM{reply: `{"number":"INV-1","currency":null,"lines":[{"item":"pen","total":2.5}],"customer":{"city":"Berlin"}}`}
+	invoice, _, err := SendStructured[structuredInvoice](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Number != "INV-1" || len(invoice.Lines) != 1 || invoice.Customer.City != "Berlin" {
+		t.Errorf("SendStructured() = %+v", invoice)
+	}
+
+	format := llm.requests[0].ResponseFormat.JSONSchema
+	if !format.Strict || format.Name != "structuredInvoice" {
+		t.Errorf("response format = %+v, want strict structuredInvoice", format)
+	}
+	data, _ := json.Marshal(format.Schema)
+	for _, want := range []string{`"required":["currency","customer","lines","number"]`, `"type":["string","null"]`, `"enum":["EUR","USD",null]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("schema %s is missing %s", data, want)
+		}
+	}
+}
+
+func TestSendStructuredUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(llm LLM) error
+		path string
+	}{
+		{"map", func(llm LLM) error {
+			_, _, err := SendStructured[struct {
+				Counts map[string]int `json:"counts"`
+			}](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "$.counts"},
+		{"interface", func(llm LLM) error {
+			_, _, err := SendStructured[struct {
+				Lines []struct {
+					Extra any `json:"extra"`
+				} `json:"lines"`
+			}](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "$.lines[].extra"},
+		{"raw message", func(llm LLM) error {
+			_, _, err := SendStructured[struct {
+				Raw json.RawMessage `json:"raw"`
+			}](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "$.raw"},
+		{"recursive", func(llm LLM) error {
+			_, _, err := SendStructured[structuredNode](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "$.children[]"},
+		{"slice root", func(llm LLM) error {
+			_, _, err := SendStructured[[]structuredLine](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "root"},
+		{"string root", func(llm LLM) error {
+			_, _, err := SendStructured[string](context.Background(), "test-model", nil, 0, "", WithLLM(llm))
+			return err
+		}, "root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{reply: "{}"}
+			err := tt.send(llm)
+			if !errors.Is(err, ErrUnsupportedSchema) || !strings.Contains(err.Error(), tt.path) {
+				t.Errorf("error = %v, want ErrUnsupportedSchema naming %s", err, tt.path)
+			}
+			if len(llm.requests) != 0 {
+				t.Errorf("sent %d requests, want none", len(llm.requests))
+			}
+		})
+	}
+}
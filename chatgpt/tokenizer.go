@@ -0,0 +1,390 @@
+package chatgpt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bundledEncodings holds the BPE vocabularies compiled into the package, stored as
+// encodings/<name>.tiktoken.gz in the gzipped tiktoken file format
+//
+//go:embed encodings
+var bundledEncodings embed.FS
+
+// ErrEncodingNotAvailable is returned when an encoding's vocabulary is neither bundled
+// nor loaded with LoadEncoding.
+var ErrEncodingNotAvailable = errors.New("encoding vocabulary not available")
+
+// spaceClass is the whitespace class of tiktoken's patterns. RE2's \s only matches ASCII
+// whitespace, while tiktoken's also matches U+0085 and the Unicode separators such as
+// no-break and ideographic spaces, the same runes as unicode.IsSpace.
+const spaceClass = `\t\n\v\f\r\x{85}\p{Z}`
+
+// encodingPatterns are the pre-tokenization patterns of the supported encodings. Both end
+// in \s+(?!\S)|\s+, which RE2 can't express; the lookahead is emulated in split.
+var encodingPatterns = map[string]*regexp.Regexp{
+	"cl100k_base": regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^` + spaceClass + `\p{L}\p{N}]+[\r\n]*|[` + spaceClass + `]*[\r\n]+|[` + spaceClass + `]+`),
+	"o200k_base": regexp.MustCompile(strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^` + spaceClass + `\p{L}\p{N}]+[\r\n/]*`,
+		`[` + spaceClass + `]*[\r\n]+`,
+		`[` + spaceClass + `]+`,
+	}, "|")),
+}
+
+// modelEncodings maps model name prefixes to their encoding, longest prefix first
+var modelEncodings = []struct{ prefix, encoding string }{
+	{"gpt-4o", "o200k_base"},
+	{"gpt-4.1", "o200k_base"},
+	{"gpt-4.5", "o200k_base"},
+	{"gpt-5", "o200k_base"},
+	{"chatgpt-4o", "o200k_base"},
+	{"o1", "o200k_base"},
+	{"o3", "o200k_base"},
+	{"o4", "o200k_base"},
+	{"gpt-4", "cl100k_base"},
+	{"gpt-3.5", "cl100k_base"},
+	{"gpt-35", "cl100k_base"},
+	{"text-embedding-", "cl100k_base"},
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*Encoding{}
+)
+
+// Encoding is a byte pair encoding tokenizer compatible with OpenAI's tiktoken.
+type Encoding struct {
+	Name string
+
+	pattern *regexp.Regexp
+	ranks   map[string]int
+	tokens  map[int]string // ranks inverted, for Decode
+}
+
+// GetEncoding returns the named encoding ("cl100k_base" or "o200k_base"), loading its
+// bundled vocabulary on first use.
+//
+// Returns:
+//   - *Encoding: The tokenizer
+//   - Error: ErrEncodingNotAvailable if the vocabulary is not bundled or loaded
+func GetEncoding(name string) (*Encoding, error) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc, nil
+	}
+	if _, ok := encodingPatterns[name]; !ok {
+		return nil, fmt.Errorf("Error Getting Encoding: unknown encoding %q", name)
+	}
+
+	file, err := bundledEncodings.Open("encodings/" + name + ".tiktoken.gz")
+	if err != nil {
+		return nil, fmt.Errorf("Error Getting Encoding %s: %w", name, ErrEncodingNotAvailable)
+	}
+	defer file.Close()
+	vocabulary, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error Reading Encoding %s: %w", name, err)
+	}
+
+	enc, err := parseEncoding(name, vocabulary)
+	if err != nil {
+		return nil, err
+	}
+	encodings[name] = enc
+	return enc, nil
+}
+
+// LoadEncoding reads a vocabulary in the tiktoken file format (base64 token and rank on
+// each line, as published for cl100k_base and o200k_base) and uses it for the named
+// encoding from then on, e.g. when it isn't bundled.
+//
+// Example Usage:
+//
+//	file, err := os.Open("/opt/models/o200k_base.tiktoken")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer file.Close()
+//
+//	if _, err := LoadEncoding("o200k_base", file); err != nil {
+//		log.Fatal(err)
+//	}
+func LoadEncoding(name string, r io.Reader) (*Encoding, error) {
+	if _, ok := encodingPatterns[name]; !ok {
+		return nil, fmt.Errorf("Error Loading Encoding: unknown encoding %q", name)
+	}
+	enc, err := parseEncoding(name, r)
+	if err != nil {
+		return nil, err
+	}
+	encodingsMu.Lock()
+	encodings[name] = enc
+	encodingsMu.Unlock()
+	return enc, nil
+}
+
+func parseEncoding(name string, r io.Reader) (*Encoding, error) {
+	enc := &Encoding{
+		Name:    name,
+		pattern: encodingPatterns[name],
+		ranks:   make(map[string]int),
+		tokens:  make(map[int]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Error Parsing Encoding %s: line %d is not a token and rank", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("Error Parsing Encoding %s: line %d: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Error Parsing Encoding %s: line %d: %w", name, line, err)
+		}
+		enc.ranks[string(token)] = rank
+		enc.tokens[rank] = string(token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error Reading Encoding %s: %w", name, err)
+	}
+	if len(enc.ranks) == 0 {
+		return nil, fmt.Errorf("Error Parsing Encoding %s: no tokens", name)
+	}
+	return enc, nil
+}
+
+// EncodingForModel returns the encoding used by model. Models it doesn't know, including
+// other providers' models, get o200k_base, so their counts are approximate.
+func EncodingForModel(model string) (*Encoding, error) {
+	// deployments and fine-tunes are named like ft:gpt-4o-mini:org::id
+	model = strings.TrimPrefix(strings.ToLower(model), "ft:")
+	for _, m := range modelEncodings {
+		if strings.HasPrefix(model, m.prefix) {
+			return GetEncoding(m.encoding)
+		}
+	}
+	return GetEncoding("o200k_base")
+}
+
+// Encode returns the tokens of text. Special tokens such as <|endoftext|> are encoded as
+// plain text.
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairMerge(piece)...)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens in text.
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// Decode turns tokens back into text, skipping tokens not in the vocabulary.
+func (e *Encoding) Decode(tokens []int) string {
+	var text strings.Builder
+	for _, token := range tokens {
+		text.WriteString(e.tokens[token])
+	}
+	return text.String()
+}
+
+// split cuts text into the pieces that are encoded independently
+func (e *Encoding) split(text string) []string {
+	var pieces []string
+	for pos := 0; pos < len(text); {
+		loc := e.pattern.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+
+		// \s+(?!\S): a run of spaces followed by more text leaves its last
+		// character to start the next piece
+		piece := text[start:end]
+		if end < len(text) && isSpaceRun(piece) {
+			if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+				end -= size
+			}
+		}
+
+		pieces = append(pieces, text[start:end])
+		pos = end
+	}
+	return pieces
+}
+
+// isSpaceRun reports whether s is whitespace without line breaks, as only the \s+
+// alternatives of the patterns match. unicode.IsSpace accepts the same runes as spaceClass.
+func isSpaceRun(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\r' || r == '\n' {
+			return false
+		}
+	}
+	return true
+}
+
+// bytePairMerge encodes a piece by repeatedly merging the adjacent pair with the lowest rank
+func (e *Encoding) bytePairMerge(piece string) []int {
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := e.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(bounds)-1)
+	for i := 0; i+1 < len(bounds); i++ {
+		if rank, ok := e.ranks[piece[bounds[i]:bounds[i+1]]]; ok {
+			tokens = append(tokens, rank)
+		}
+	}
+	return tokens
+}
+
+// CountTokens counts the prompt tokens messages will use with model, including the
+// per-message framing of the chat format and the priming of the reply. Text, tool calls
+// and images are counted (images from their size and detail, 765 tokens when unknown);
+// audio and file parts are not.
+//
+// Parameters:
+//   - model: The model the messages will be sent to, which selects the encoding
+//   - messages: The messages to count
+//
+// Returns:
+//   - int: The number of prompt tokens
+//   - Error: ErrEncodingNotAvailable if the model's vocabulary isn't bundled or loaded
+//
+// Example Usage:
+//
+//	n, err := CountTokens("gpt-4o", messages)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if n > 100000 {
+//		messages = messages[len(messages)-20:]
+//	}
+func CountTokens(model string, messages []Message) (int, error) {
+	enc, err := EncodingForModel(model)
+	if err != nil {
+		return 0, err
+	}
+
+	tokens := 3 // every reply is primed with <|start|>assistant<|message|>
+	for _, msg := range messages {
+		tokens += 3 + enc.Count(msg.Role)
+		if len(msg.Parts) == 0 {
+			tokens += enc.Count(msg.Content)
+		}
+		for _, part := range msg.Parts {
+			switch part.Type {
+			case "text":
+				tokens += enc.Count(part.Text)
+			case "image_url":
+				if part.ImageURL != nil {
+					tokens += imageTokens(part.ImageURL)
+				}
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			tokens += 3 + enc.Count(call.Function.Name) + enc.Count(call.Function.Arguments)
+		}
+	}
+	return tokens, nil
+}
+
+// imageTokens estimates the tokens of an image: 85 at low detail, otherwise 85 plus 170
+// per 512px tile after scaling it to fit 2048x2048 and then to 768px on its short side
+func imageTokens(img *ImageURL) int {
+	if img.Detail == "low" {
+		return 85
+	}
+	_, data, ok := parseDataURL(img.URL)
+	if !ok {
+		return 765
+	}
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 765
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return 765
+	}
+
+	w, h := float64(cfg.Width), float64(cfg.Height)
+	if scale := 2048 / math.Max(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	if scale := 768 / math.Min(w, h); scale < 1 {
+		w, h = w*scale, h*scale
+	}
+	tiles := math.Ceil(w/512) * math.Ceil(h/512)
+	return 85 + 170*int(tiles)
+}
+
+// countTokens counts the prompt tokens of messages, or estimates them at roughly four
+// characters per token when the model's encoding isn't available
+func countTokens(model string, messages []Message) int {
+	if tokens, err := CountTokens(model, messages); err == nil {
+		return tokens
+	}
+	tokens := 3
+	for _, msg := range messages {
+		tokens += 4 + (len(msg.Role)+len(msg.Text())+3)/4
+	}
+	return tokens
+}
+
+// countTextTokens counts the tokens in text like countTokens
+func countTextTokens(model string, text string) int {
+	if enc, err := EncodingForModel(model); err == nil {
+		return enc.Count(text)
+	}
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,90 @@
+package chatgpt
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tiktokenVectors are the token IDs tiktoken produces for each input
+var tiktokenVectors = map[string][]struct {
+	text   string
+	tokens []int
+}{
+	"cl100k_base": {
+		{"Hello, world!", []int{9906, 11, 1917, 0}},
+		{"I'm sure they'll say it's fine, don't you think?", []int{40, 2846, 2771, 814, 3358, 2019, 433, 596, 7060, 11, 1541, 956, 499, 1781, 30}},
+		{"The year 2024 had 366 days and 1234567 seconds... roughly.", []int{791, 1060, 220, 2366, 19, 1047, 220, 18044, 2919, 323, 220, 4513, 10961, 22, 6622, 1131, 17715, 13}},
+		{"    indented code\n\tif x := f(); x != nil {\n\t\treturn x\n\t}\n", []int{262, 1280, 16243, 2082, 198, 748, 865, 1703, 282, 2178, 865, 976, 2139, 341, 197, 862, 865, 198, 197, 534}},
+		{"trailing spaces   \n\n\nand lines  ", []int{376, 14612, 12908, 262, 1432, 438, 5238, 256}},
+		{"non\u00a0breaking\u00a0\u00a0spaces and\u3000ideographic\u2003em  \u00a0x", []int{6414, 4194, 37757, 4194, 4194, 45385, 323, 23249, 95107, 378, 225, 336, 256, 4194, 87}},
+		{"\u00a0\u00a0\u00a0leading", []int{9421, 4194, 21307}},
+		{"HelloWorld CamelCaseWords URLs like https://example.com/a/b?c=d", []int{9906, 10343, 69254, 4301, 24390, 36106, 1093, 3788, 1129, 8858, 916, 14520, 3554, 30, 66, 26477}},
+		{"日本語のテキストと中文字符", []int{9080, 22656, 45918, 252, 16144, 57933, 62903, 71634, 19732, 16325, 17161, 49491}},
+		{"emoji 🎉🚀 and accents: café naïve Ω≈ç√", []int{38623, 11410, 236, 231, 9468, 248, 222, 323, 59570, 25, 53050, 95980, 588, 8008, 102, 60094, 230, 3209, 22447, 248}},
+		{"<|endoftext|> is plain text here", []int{27, 91, 8862, 728, 428, 91, 29, 374, 14733, 1495, 1618}},
+		{"", []int{}},
+	},
+	"o200k_base": {
+		{"Hello, world!", []int{13225, 11, 2375, 0}},
+		{"I'm sure they'll say it's fine, don't you think?", []int{15390, 3239, 57956, 2891, 4275, 8975, 11, 4128, 481, 2411, 30}},
+		{"The year 2024 had 366 days and 1234567 seconds... roughly.", []int{976, 1284, 220, 1323, 19, 1458, 220, 32465, 3376, 326, 220, 7633, 19354, 22, 12068, 1008, 36144, 13}},
+		{"    indented code\n\tif x := f(); x != nil {\n\t\treturn x\n\t}\n", []int{271, 1383, 23537, 3490, 198, 1224, 1215, 3405, 285, 4177, 1215, 1666, 4038, 405, 197, 1393, 1215, 198, 197, 739}},
+		{"trailing spaces   \n\n\nand lines  ", []int{371, 24408, 18608, 271, 2499, 427, 8698, 256}},
+		{"non\u00a0breaking\u00a0\u00a0spaces and\u3000ideographic\u2003em  \u00a0x", []int{11741, 5310, 58786, 5310, 5310, 78711, 326, 1397, 617, 19045, 33203, 347, 256, 5310, 87}},
+		{"\u00a0\u00a0\u00a0leading", []int{17725, 5310, 51812}},
+		{"HelloWorld CamelCaseWords URLs like https://example.com/a/b?c=d", []int{13225, 13046, 112127, 6187, 27321, 67852, 1299, 5918, 1684, 18582, 1136, 23839, 7611, 30, 66, 56413}},
+		{"日本語のテキストと中文字符", []int{9048, 40909, 3385, 16056, 18368, 38236, 5330, 10667, 67951}},
+		{"emoji 🎉🚀 and accents: café naïve Ω≈ç√", []int{75339, 139786, 231, 112927, 222, 326, 73830, 25, 30469, 153475, 737, 159488, 171441, 704, 103946}},
+		{"<|endoftext|> is plain text here", []int{27, 91, 419, 1440, 919, 91, 29, 382, 21402, 2201, 2105}},
+		{"", []int{}},
+	},
+}
+
+func TestEncodingMatchesTiktoken(t *testing.T) {
+	for name, vectors := range tiktokenVectors {
+		enc, err := GetEncoding(name)
+		if err != nil {
+			t.Fatalf("GetEncoding(%q) error = %v", name, err)
+		}
+		for _, v := range vectors {
+			got := enc.Encode(v.text)
+			if !slices.Equal(got, v.tokens) {
+				t.Errorf("%s Encode(%q) = %v, want %v", name, v.text, got, v.tokens)
+			}
+			if count := enc.Count(v.text); count != len(v.tokens) {
+				t.Errorf("%s Count(%q) = %d, want %d", name, v.text, count, len(v.tokens))
+			}
+			if text := enc.Decode(v.tokens); text != v.text {
+				t.Errorf("%s Decode(%v) = %q, want %q", name, v.tokens, text, v.text)
+			}
+		}
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	for model, want := range map[string]string{
+		"gpt-4o-mini":          "o200k_base",
+		"ft:gpt-4o-mini:org::": "o200k_base",
+		"gpt-4-turbo":          "cl100k_base",
+		"gpt-3.5-turbo":        "cl100k_base",
+		"claude-sonnet-4":      "o200k_base",
+	} {
+		enc, err := EncodingForModel(model)
+		if err != nil || enc.Name != want {
+			t.Errorf("EncodingForModel(%q) = %v, %v, want %s", model, enc, err, want)
+		}
+	}
+}
+
+// split relies on the patterns' whitespace class and unicode.IsSpace agreeing
+func TestSpaceClassMatchesIsSpace(t *testing.T) {
+	class := regexp.MustCompile(`^[` + spaceClass + `]$`)
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if utf8.ValidRune(r) && class.MatchString(string(r)) != unicode.IsSpace(r) {
+			t.Errorf("%U: whitespace class = %v, unicode.IsSpace = %v", r, !unicode.IsSpace(r), unicode.IsSpace(r))
+		}
+	}
+}
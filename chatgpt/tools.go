@@ -0,0 +1,176 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ToolRegistry holds Go functions the model can call. Register them with RegisterTool,
+// then let Run execute the calls the model asks for until it answers.
+type ToolRegistry struct {
+	MaxSteps int // requests Run may send before giving up, defaults to 10
+
+	mu    sync.RWMutex
+	tools map[string]registeredTool
+	order []string
+}
+
+type registeredTool struct {
+	spec FunctionSpec
+	call func(ctx context.Context, arguments string) (string, error)
+}
+
+// NewToolRegistry creates an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]registeredTool)}
+}
+
+// RegisterTool adds fn to the registry under name. Its parameters schema is derived from
+// Args (see SchemaFor), the model's arguments are unmarshaled into an Args, and the
+// result is sent back to the model as JSON (or as-is for a string).
+//
+// Example Usage:
+//
+//	type WeatherArgs struct {
+//		City string `json:"city" description:"City name, e.g. Berlin"`
+//		Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+//	}
+//
+//	tools := NewToolRegistry()
+//	RegisterTool(tools, "get_weather", "Current weather for a city", func(ctx context.Context, args WeatherArgs) (Weather, error) {
+//		return weatherService.Current(ctx, args.City, args.Unit)
+//	})
+func RegisterTool[Args any, Result any](r *ToolRegistry, name string, description string, fn func(ctx context.Context, args Args) (Result, error)) {
+	tool := registeredTool{
+		spec: FunctionSpec{
+			Name:        name,
+			Description: description,
+			Parameters:  SchemaFor[Args](),
+		},
+		call: func(ctx context.Context, arguments string) (string, error) {
+			var args Args
+			if arguments != "" {
+				if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+					return "", fmt.Errorf("invalid arguments for %s: %w", name, err)
+				}
+			}
+			result, err := fn(ctx, args)
+			if err != nil {
+				return "", err
+			}
+			if s, ok := any(result).(string); ok {
+				return s, nil
+			}
+			data, err := json.Marshal(result)
+			if err != nil {
+				return "", fmt.Errorf("Error Marshaling %s Result: %w", name, err)
+			}
+			return string(data), nil
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.tools[name] = tool
+}
+
+// Tools returns the registered tools in registration order, ready for ChatRequest.Tools.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]Tool, len(r.order))
+	for i, name := range r.order {
+		tools[i] = Tool{Type: "function", Function: r.tools[name].spec}
+	}
+	return tools
+}
+
+// Call executes a tool call and returns the "tool" message answering it. Unknown tools,
+// bad arguments and errors returned by the function are reported to the model in the
+// message content so it can recover; the error is returned as well.
+func (r *ToolRegistry) Call(ctx context.Context, call ToolCall) (Message, error) {
+	r.mu.RLock()
+	tool, ok := r.tools[call.Function.Name]
+	r.mu.RUnlock()
+
+	var content string
+	var err error
+	if !ok {
+		err = fmt.Errorf("unknown tool %q", call.Function.Name)
+	} else {
+		content, err = tool.call(ctx, call.Function.Arguments)
+	}
+	if err != nil {
+		content = "Error: " + err.Error()
+	}
+	return Message{Role: "tool", Content: content, ToolCallID: call.ID}, err
+}
+
+// Run sends messages with the registered tools, executes every tool call the model
+// makes, feeds the results back and repeats until the model replies without calling
+// a tool. Tool errors are passed to the model rather than ending the run.
+//
+// Parameters:
+//   - ctx: Context for the requests and the tool functions
+//   - model: The GPT model you want to use (gpt-4o)
+//   - messages: The messages/context to send to gpt
+//   - tmp: The temperature for gpt (0 = detreministic | 1 = random)
+//   - key: The openAPI key to use in the requests
+//   - opts: Further request options for every request, e.g. WithLLM or WithParallelToolCalls
+//
+// Returns:
+//   - Response: The final response, whose first choice holds the answer
+//   - []Message: The full transcript, including the tool calls and results
+//   - Error: Any errors that occur during execution, or running out of MaxSteps
+//
+// Example Usage:
+//
+//	messages := []Message{{Role: "user", Content: "Do I need an umbrella in Berlin today?"}}
+//	resp, transcript, err := tools.Run(ctx, "gpt-4o", messages, 0, os.Getenv("OPENAI_API_KEY"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(resp.Choices[0].Message.Content)
+func (r *ToolRegistry) Run(ctx context.Context, model string, messages []Message, tmp float32, key string, opts ...RequestOption) (Response, []Message, error) {
+	maxSteps := r.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 10
+	}
+	transcript := append([]Message(nil), messages...)
+
+	for step := 0; step < maxSteps; step++ {
+		body := ChatRequest{
+			Model:       model,
+			Messages:    transcript,
+			Temperature: tmp,
+			Tools:       r.Tools(),
+		}
+		resp, err := sendChat(ctx, body, key, opts)
+		if err != nil {
+			return Response{}, transcript, err
+		}
+		if len(resp.Choices) == 0 {
+			return Response{}, transcript, fmt.Errorf("Error Running Tools: response has no choices")
+		}
+
+		reply := resp.Choices[0].Message
+		transcript = append(transcript, reply)
+		if len(reply.ToolCalls) == 0 {
+			return resp, transcript, nil
+		}
+
+		for _, call := range reply.ToolCalls {
+			result, _ := r.Call(ctx, call)
+			transcript = append(transcript, result)
+		}
+		if err := ctx.Err(); err != nil {
+			return Response{}, transcript, err
+		}
+	}
+	return Response{}, transcript, fmt.Errorf("Error Running Tools: no final answer after %d requests", maxSteps)
+}
@@ -0,0 +1,302 @@
+package chatgpt
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Price is what a model costs in USD per million tokens.
+type Price struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input,omitempty"` // prompt cache hits, defaults to Input
+	Output      float64 `json:"output"`
+}
+
+// DefaultPrices are list prices per million tokens, keyed by model name or prefix. Prices
+// change; pass your own table to NewUsageTracker or edit UsageTracker.Prices.
+var DefaultPrices = map[string]Price{
+	"gpt-4o":                 {Input: 2.50, CachedInput: 1.25, Output: 10.00},
+	"gpt-4o-mini":            {Input: 0.15, CachedInput: 0.075, Output: 0.60},
+	"gpt-4.1":                {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+	"gpt-4.1-mini":           {Input: 0.40, CachedInput: 0.10, Output: 1.60},
+	"gpt-4.1-nano":           {Input: 0.10, CachedInput: 0.025, Output: 0.40},
+	"gpt-4-turbo":            {Input: 10.00, Output: 30.00},
+	"gpt-4":                  {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+	"o1":                     {Input: 15.00, CachedInput: 7.50, Output: 60.00},
+	"o1-mini":                {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o1-pro":                 {Input: 150.00, Output: 600.00},
+	"o3":                     {Input: 2.00, CachedInput: 0.50, Output: 8.00},
+	"o3-mini":                {Input: 1.10, CachedInput: 0.55, Output: 4.40},
+	"o3-pro":                 {Input: 20.00, Output: 80.00},
+	"o4-mini":                {Input: 1.10, CachedInput: 0.275, Output: 4.40},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"text-embedding-ada-002": {Input: 0.10},
+	"claude-opus-4":          {Input: 15.00, CachedInput: 1.50, Output: 75.00},
+	"claude-sonnet-4":        {Input: 3.00, CachedInput: 0.30, Output: 15.00},
+	"claude-3-7-sonnet":      {Input: 3.00, CachedInput: 0.30, Output: 15.00},
+	"claude-3-5-sonnet":      {Input: 3.00, CachedInput: 0.30, Output: 15.00},
+	"claude-3-5-haiku":       {Input: 0.80, CachedInput: 0.08, Output: 4.00},
+}
+
+// ErrBudgetExceeded is returned once a UsageTracker's spend reaches its Budget.
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// ModelUsage is the usage and cost a UsageTracker has recorded for one model.
+type ModelUsage struct {
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CachedTokens     int     `json:"cached_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`   // USD
+	Priced           bool    `json:"priced"` // false when the model has no price and its cost is unknown
+}
+
+// UsageTracker adds up the Usage of requests per model and prices it, e.g. to report the
+// cost of a job. With a Budget, requests are refused with ErrBudgetExceeded once the
+// spend (plus the estimated cost of the next prompt) would pass it.
+//
+// A UsageTracker is safe for concurrent use.
+type UsageTracker struct {
+	Prices map[string]Price // by model name or prefix, "gpt-4o" also prices "gpt-4o-2024-08-06" (see price)
+	Budget float64          // USD ceiling, 0 for none
+
+	mu     sync.Mutex
+	models map[string]*ModelUsage
+}
+
+// NewUsageTracker creates a tracker with a copy of DefaultPrices and the given budget.
+//
+// Parameters:
+//   - budget: The most the tracked requests may cost in USD, 0 for no limit
+//
+// Returns:
+//   - *UsageTracker: The tracker, see Track and WithUsageTracker
+//
+// Example Usage:
+//
+//	tracker := NewUsageTracker(5.00)
+//	tracker.Prices["my-finetune"] = Price{Input: 3.00, Output: 12.00}
+//
+//	for _, ticket := range tickets {
+//		_, err := Chat(ctx, prompt(ticket), WithModel("gpt-4o-mini"), WithUsageTracker(tracker))
+//		if errors.Is(err, ErrBudgetExceeded) {
+//			log.Println("stopping, budget spent")
+//			break
+//		}
+//	}
+//	fmt.Print(tracker.Report())
+func NewUsageTracker(budget float64) *UsageTracker {
+	return &UsageTracker{Prices: maps.Clone(DefaultPrices), Budget: budget}
+}
+
+// Add records a request's usage for model. It returns ErrBudgetExceeded if the total
+// cost now exceeds the budget; the usage is recorded either way.
+func (t *UsageTracker) Add(model string, usage Usage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.models == nil {
+		t.models = make(map[string]*ModelUsage)
+	}
+	m, ok := t.models[model]
+	if !ok {
+		m = &ModelUsage{}
+		t.models[model] = m
+	}
+
+	cached := 0
+	if usage.PromptTokensDetails != nil {
+		cached = usage.PromptTokensDetails.CachedTokens
+	}
+	m.Requests++
+	m.PromptTokens += usage.PromptTokens
+	m.CachedTokens += cached
+	m.CompletionTokens += usage.CompletionTokens
+
+	price, priced := t.price(model)
+	m.Priced = priced
+	m.Cost += price.cost(usage.PromptTokens-cached, cached, usage.CompletionTokens)
+
+	if spent := t.cost(); t.Budget > 0 && spent > t.Budget {
+		return fmt.Errorf("%w: spent $%.4f of $%.2f", ErrBudgetExceeded, spent, t.Budget)
+	}
+	return nil
+}
+
+// Allow reports with ErrBudgetExceeded whether sending promptTokens more to model would
+// pass the budget. It is nil when there is no budget.
+func (t *UsageTracker) Allow(model string, promptTokens int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Budget <= 0 {
+		return nil
+	}
+	price, _ := t.price(model)
+	spent := t.cost()
+	if estimate := price.cost(promptTokens, 0, 0); spent >= t.Budget || spent+estimate > t.Budget {
+		return fmt.Errorf("%w: spent $%.4f of $%.2f", ErrBudgetExceeded, spent, t.Budget)
+	}
+	return nil
+}
+
+// Cost returns the total cost recorded so far in USD.
+func (t *UsageTracker) Cost() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cost()
+}
+
+// Usage returns the usage recorded so far by model.
+func (t *UsageTracker) Usage() map[string]ModelUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	usage := make(map[string]ModelUsage, len(t.models))
+	for model, m := range t.models {
+		usage[model] = *m
+	}
+	return usage
+}
+
+// Report summarizes the usage and cost per model and in total, one line each.
+func (t *UsageTracker) Report() string {
+	usage := t.Usage()
+	var report strings.Builder
+	for _, model := range slices.Sorted(maps.Keys(usage)) {
+		m := usage[model]
+		cost := fmt.Sprintf("$%.4f", m.Cost)
+		if !m.Priced {
+			cost = "no price"
+		}
+		fmt.Fprintf(&report, "%s: %d requests, %d prompt (%d cached) + %d completion tokens, %s\n",
+			model, m.Requests, m.PromptTokens, m.CachedTokens, m.CompletionTokens, cost)
+	}
+	fmt.Fprintf(&report, "total: $%.4f", t.Cost())
+	if t.Budget > 0 {
+		fmt.Fprintf(&report, " of $%.2f budget", t.Budget)
+	}
+	report.WriteString("\n")
+	return report.String()
+}
+
+// Reset forgets the recorded usage, e.g. between jobs.
+func (t *UsageTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.models = nil
+}
+
+// Track wraps llm so every request is checked against the budget before it is sent
+// (with its prompt counted by CountTokens) and its usage is recorded afterwards.
+func (t *UsageTracker) Track(llm LLM) LLM {
+	return &trackedLLM{llm: llm, tracker: t}
+}
+
+// WithUsageTracker records the request's usage in t and refuses it once t's budget is spent.
+func WithUsageTracker(t *UsageTracker) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.tracker = t
+	}
+}
+
+func (t *UsageTracker) cost() float64 {
+	var total float64
+	for _, m := range t.models {
+		total += m.Cost
+	}
+	return total
+}
+
+// price finds the price for model by exact name, then by the longest matching prefix that
+// ends where a "-" or ":" suffix starts. Variants priced differently from their base model,
+// such as "o3-pro", need their own entry.
+func (t *UsageTracker) price(model string) (Price, bool) {
+	if price, ok := t.Prices[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range t.Prices {
+		rest, ok := strings.CutPrefix(model, name)
+		if ok && (rest[0] == '-' || rest[0] == ':') && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t.Prices[best], true
+}
+
+func (p Price) cost(prompt int, cached int, completion int) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(prompt)*p.Input + float64(cached)*cachedPrice + float64(completion)*p.Output) / 1e6
+}
+
+// trackedLLM is an LLM whose requests are checked and recorded by a UsageTracker
+type trackedLLM struct {
+	llm     LLM
+	tracker *UsageTracker
+}
+
+// requestModel returns the model req is sent to by llm, which fills in its default model
+// when the request doesn't name one
+func requestModel(llm LLM, req ChatRequest) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	switch l := llm.(type) {
+	case *OpenAI:
+		return l.Model
+	case *AzureOpenAI:
+		return cmp.Or(l.Model, l.Deployment)
+	case *Anthropic:
+		return l.Model
+	case *trackedLLM:
+		return requestModel(l.llm, req)
+	}
+	return ""
+}
+
+func (l *trackedLLM) Chat(ctx context.Context, req ChatRequest) (Response, error) {
+	model := requestModel(l.llm, req)
+	if err := l.tracker.Allow(model, countTokens(model, req.Messages)); err != nil {
+		return Response{}, err
+	}
+	resp, err := l.llm.Chat(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Usage != nil {
+		// going over the budget here refuses the next request rather than failing this one
+		l.tracker.Add(cmp.Or(resp.Model, model), *resp.Usage)
+	}
+	return resp, nil
+}
+
+func (l *trackedLLM) Stream(ctx context.Context, req ChatRequest) iter.Seq2[Chunk, error] {
+	return func(yield func(Chunk, error) bool) {
+		model := requestModel(l.llm, req)
+		if err := l.tracker.Allow(model, countTokens(model, req.Messages)); err != nil {
+			yield(Chunk{}, err)
+			return
+		}
+		for chunk, err := range l.llm.Stream(ctx, req) {
+			if err == nil && chunk.Usage != nil {
+				l.tracker.Add(cmp.Or(chunk.Model, model), *chunk.Usage)
+			}
+			if !yield(chunk, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUsageTrackerPrice(t *testing.T) {
+	tracker := NewUsageTracker(0)
+	tests := []struct {
+		model  string
+		want   Price
+		priced bool
+	}{
+		{"gpt-4o", DefaultPrices["gpt-4o"], true},
+		{"gpt-4o-2024-08-06", DefaultPrices["gpt-4o"], true},
+		{"gpt-4o-mini-2024-07-18", DefaultPrices["gpt-4o-mini"], true},
+		{"o3-pro", DefaultPrices["o3-pro"], true},
+		{"o3-pro-2025-06-10", DefaultPrices["o3-pro"], true},
+		{"o3-2025-04-16", DefaultPrices["o3"], true},
+		{"claude-sonnet-4-20250514", DefaultPrices["claude-sonnet-4"], true},
+		{"gpt-4.5-preview", Price{}, false}, // not gpt-4
+		{"o3x", Price{}, false},
+		{"unknown", Price{}, false},
+	}
+	for _, tt := range tests {
+		price, priced := tracker.price(tt.model)
+		if price != tt.want || priced != tt.priced {
+			t.Errorf("price(%q) = %+v, %v, want %+v, %v", tt.model, price, priced, tt.want, tt.priced)
+		}
+	}
+}
+
+func TestUsageTrackerAllowsDefaultModel(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "unexpected request", http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	// the request names no model, so the prompt is priced at the provider's gpt-4
+	tracker := NewUsageTracker(0.001)
+	llm := tracker.Track(&OpenAI{BaseURL: server.URL, Model: "gpt-4"})
+	req := ChatRequest{Messages: []Message{{Role: "user", Content: strings.Repeat("count these words ", 100)}}}
+
+	if _, err := llm.Chat(context.Background(), req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Chat() error = %v, want ErrBudgetExceeded", err)
+	}
+	for _, err := range llm.Stream(context.Background(), req) {
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Stream() error = %v, want ErrBudgetExceeded", err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("server called %d times, want the requests refused before sending", calls)
+	}
+}
+
+func TestRequestModel(t *testing.T) {
+	tests := []struct {
+		llm  LLM
+		req  ChatRequest
+		want string
+	}{
+		{&OpenAI{Model: "gpt-4o"}, ChatRequest{}, "gpt-4o"},
+		{&OpenAI{Model: "gpt-4o"}, ChatRequest{Model: "o3"}, "o3"},
+		{&AzureOpenAI{Deployment: "gpt-4o-mini"}, ChatRequest{}, "gpt-4o-mini"},
+		{&Anthropic{Model: "claude-sonnet-4"}, ChatRequest{}, "claude-sonnet-4"},
+		{NewUsageTracker(0).Track(&Anthropic{Model: "claude-opus-4"}), ChatRequest{}, "claude-opus-4"},
+		{&fakeLLM{}, ChatRequest{}, ""},
+	}
+	for _, tt := range tests {
+		if got := requestModel(tt.llm, tt.req); got != tt.want {
+			t.Errorf("requestModel(%T, %q) = %q, want %q", tt.llm, tt.req.Model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+)
+
+// VectorIndex is an in-memory index of items by embedding, searched by cosine
+// similarity. Vectors are normalized when added, so every vector must come from the
+// same embedding model. Save and LoadVectorIndex persist it as JSON, items included.
+type VectorIndex[T any] struct {
+	Model   string           `json:"model,omitempty"` // embedding model used by AddItems and Query
+	Entries []VectorEntry[T] `json:"entries"`
+
+	mu  sync.RWMutex
+	ids map[string]int // entry position by ID
+}
+
+type VectorEntry[T any] struct {
+	ID     string    `json:"id"`
+	Vector []float32 `json:"vector"` // unit length
+	Item   T         `json:"item"`
+}
+
+type SearchResult[T any] struct {
+	ID    string
+	Item  T
+	Score float32 // cosine similarity, 1 for an identical direction
+}
+
+// NewVectorIndex creates an empty index for vectors from model.
+//
+// Example Usage:
+//
+//	type Ticket struct {
+//		ID      string `csv:"id"`
+//		Subject string `csv:"subject"`
+//		Body    string `csv:"body"`
+//	}
+//
+//	tickets, err := csv.ReadCSV("tickets.csv", &Ticket{})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	embedder := &OpenAI{APIKey: os.Getenv("OPENAI_API_KEY")}
+//	index := NewVectorIndex[*Ticket]("text-embedding-3-small")
+//	_, err = index.AddItems(ctx, embedder, tickets,
+//		func(t *Ticket) string { return t.ID },
+//		func(t *Ticket) string { return t.Subject + "\n" + t.Body })
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	index.Save("tickets.index.json")
+//
+//	results, err := index.Query(ctx, embedder, "customer never got their refund", 5)
+//	for _, r := range results {
+//		fmt.Printf("%.3f %s\n", r.Score, r.Item.Subject)
+//	}
+func NewVectorIndex[T any](model string) *VectorIndex[T] {
+	return &VectorIndex[T]{Model: model, ids: make(map[string]int)}
+}
+
+// LoadVectorIndex reads an index written by Save.
+func LoadVectorIndex[T any](path string) (*VectorIndex[T], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error Reading Vector Index: %w", err)
+	}
+	index := &VectorIndex[T]{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("Error Unmarshaling Vector Index: %w", err)
+	}
+	index.ids = make(map[string]int, len(index.Entries))
+	for i, entry := range index.Entries {
+		index.ids[entry.ID] = i
+	}
+	return index, nil
+}
+
+// Save writes the index to path as JSON.
+func (ix *VectorIndex[T]) Save(path string) error {
+	ix.mu.RLock()
+	data, err := json.Marshal(ix)
+	ix.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("Error Marshaling Vector Index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("Error Writing Vector Index: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the index.
+func (ix *VectorIndex[T]) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.Entries)
+}
+
+// Add stores item under id with its embedding, replacing any entry with the same id.
+// The vector must be non-zero and have the same dimensions as the vectors already added.
+func (ix *VectorIndex[T]) Add(id string, vector []float32, item T) error {
+	unit, err := normalize(vector)
+	if err != nil {
+		return fmt.Errorf("Error Adding %s to Vector Index: %w", id, err)
+	}
+	return ix.add([]VectorEntry[T]{{ID: id, Vector: unit, Item: item}})
+}
+
+// add stores normalized entries, all or none of them: their dimensions are checked
+// before any is stored
+func (ix *VectorIndex[T]) add(entries []VectorEntry[T]) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.ids == nil {
+		ix.ids = make(map[string]int)
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	dims := len(entries[0].Vector)
+	if len(ix.Entries) > 0 {
+		dims = len(ix.Entries[0].Vector)
+	}
+	for _, entry := range entries {
+		if len(entry.Vector) != dims {
+			return fmt.Errorf("Error Adding %s to Vector Index: vector has %d dimensions, index has %d", entry.ID, len(entry.Vector), dims)
+		}
+	}
+
+	for _, entry := range entries {
+		if i, ok := ix.ids[entry.ID]; ok {
+			ix.Entries[i] = entry
+			continue
+		}
+		ix.ids[entry.ID] = len(ix.Entries)
+		ix.Entries = append(ix.Entries, entry)
+	}
+	return nil
+}
+
+// AddItems embeds text(item) for every item with e (batched, see EmbedWith) and adds
+// each one under id(item).
+//
+// Returns:
+//   - Usage: The tokens used to embed the items
+//   - Error: Any errors that occur during execution, in which case nothing is added
+func (ix *VectorIndex[T]) AddItems(ctx context.Context, e Embedder, items []T, id func(T) string, text func(T) string) (Usage, error) {
+	inputs := make([]string, len(items))
+	for i, item := range items {
+		inputs[i] = text(item)
+	}
+	vectors, usage, err := EmbedWith(ctx, e, ix.Model, inputs)
+	if err != nil {
+		return usage, err
+	}
+
+	// every vector is checked before the first is stored, so a bad one adds nothing
+	entries := make([]VectorEntry[T], len(items))
+	for i, item := range items {
+		entries[i] = VectorEntry[T]{ID: id(item), Item: item}
+		if entries[i].Vector, err = normalize(vectors[i]); err != nil {
+			return usage, fmt.Errorf("Error Adding %s to Vector Index: %w", entries[i].ID, err)
+		}
+	}
+	return usage, ix.add(entries)
+}
+
+// Remove deletes the entry stored under id, reporting whether there was one.
+func (ix *VectorIndex[T]) Remove(id string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i, ok := ix.ids[id]
+	if !ok {
+		return false
+	}
+	ix.Entries = slices.Delete(ix.Entries, i, i+1)
+	delete(ix.ids, id)
+	for j := i; j < len(ix.Entries); j++ {
+		ix.ids[ix.Entries[j].ID] = j
+	}
+	return true
+}
+
+// Search returns the k entries most similar to query, best first.
+func (ix *VectorIndex[T]) Search(query []float32, k int) ([]SearchResult[T], error) {
+	unit, err := normalize(query)
+	if err != nil {
+		return nil, fmt.Errorf("Error Searching Vector Index: %w", err)
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	if len(ix.Entries) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if len(ix.Entries[0].Vector) != len(unit) {
+		return nil, fmt.Errorf("Error Searching Vector Index: query has %d dimensions, index has %d", len(unit), len(ix.Entries[0].Vector))
+	}
+
+	results := make([]SearchResult[T], len(ix.Entries))
+	for i, entry := range ix.Entries {
+		results[i] = SearchResult[T]{ID: entry.ID, Item: entry.Item, Score: dot(unit, entry.Vector)}
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult[T]) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return results[:min(k, len(results))], nil
+}
+
+// Query embeds text with e and the index's model and returns the k most similar entries.
+func (ix *VectorIndex[T]) Query(ctx context.Context, e Embedder, text string, k int) ([]SearchResult[T], error) {
+	vectors, _, err := EmbedWith(ctx, e, ix.Model, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return ix.Search(vectors[0], k)
+}
+
+// normalize returns a unit length copy of v
+func normalize(v []float32) ([]float32, error) {
+	norm := math.Sqrt(float64(dot(v, v)))
+	if norm == 0 {
+		return nil, fmt.Errorf("vector is empty or zero")
+	}
+	unit := make([]float32, len(v))
+	for i, x := range v {
+		unit[i] = float32(float64(x) / norm)
+	}
+	return unit, nil
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,68 @@
+package chatgpt
+
+import (
+	"context"
+	"testing"
+)
+
+// fakeEmbedder embeds every input as the vector listed for it
+type fakeEmbedder map[string][]float32
+
+func (f fakeEmbedder) Embed(ctx context.Context, req EmbeddingRequest) (EmbeddingResponse, error) {
+	var resp EmbeddingResponse
+	for i, input := range req.Input {
+		resp.Data = append(resp.Data, Embedding{Index: i, Embedding: f[input]})
+	}
+	return resp, nil
+}
+
+func TestVectorIndexSearch(t *testing.T) {
+	embedder := fakeEmbedder{"cats": {1, 0}, "dogs": {0.8, 0.6}, "cars": {0, 3}, "pets": {1, 0.1}}
+	index := NewVectorIndex[string]("test-model")
+	if _, err := index.AddItems(context.Background(), embedder, []string{"cats", "dogs", "cars"},
+		func(s string) string { return s }, func(s string) string { return s }); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := index.Query(context.Background(), embedder, "pets", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != "cats" || results[1].ID != "dogs" {
+		t.Errorf("Query() = %+v, want cats then dogs", results)
+	}
+}
+
+func TestVectorIndexAddItemsAddsAllOrNothing(t *testing.T) {
+	embedder := fakeEmbedder{"a": {1, 0}, "b": {0, 1}, "zero": {0, 0}, "wide": {1, 0, 0}}
+	tests := map[string][]string{
+		"zero vector":         {"a", "b", "zero"},
+		"mismatched in batch": {"a", "b", "wide"},
+	}
+	for name, items := range tests {
+		t.Run(name, func(t *testing.T) {
+			index := NewVectorIndex[string]("test-model")
+			_, err := index.AddItems(context.Background(), embedder, items,
+				func(s string) string { return s }, func(s string) string { return s })
+			if err == nil {
+				t.Fatal("AddItems() succeeded, want an error")
+			}
+			if index.Len() != 0 {
+				t.Errorf("index has %d entries after a failed AddItems, want 0", index.Len())
+			}
+		})
+	}
+
+	// a batch that doesn't match the entries already in the index
+	index := NewVectorIndex[string]("test-model")
+	if err := index.Add("wide", embedder["wide"], "wide"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := index.AddItems(context.Background(), embedder, []string{"a", "b"},
+		func(s string) string { return s }, func(s string) string { return s }); err == nil {
+		t.Fatal("AddItems() succeeded, want a dimension error")
+	}
+	if index.Len() != 1 {
+		t.Errorf("index has %d entries, want only the original one", index.Len())
+	}
+}
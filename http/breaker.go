@@ -0,0 +1,359 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned (wrapped in a *CircuitOpenError) when a request is
+// rejected because its circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError reports which circuit rejected a request and when it will
+// let a probe through again. It matches ErrCircuitOpen with errors.Is.
+type CircuitOpenError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker is open for %s, retry in %s", e.Key, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a single circuit.
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // requests flow, outcomes are counted
+	CircuitOpen                         // requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen                     // a limited number of probes test the downstream
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// BreakerConfig configures a CircuitBreaker. Zero values use the defaults noted.
+type BreakerConfig struct {
+	FailureRate    float64       // failure ratio (0-1) in Window that opens the circuit, defaults to 0.5
+	MinRequests    int           // requests needed in Window before the rate is considered, defaults to 10
+	Window         time.Duration // rolling window the failure rate is measured over, defaults to 60s, at least 10ns
+	Cooldown       time.Duration // how long the circuit stays open before probing, defaults to 30s
+	HalfOpenProbes int           // concurrent probes while half-open, all must succeed to close, defaults to 1
+
+	// Key picks the circuit for a request, defaults to the request's host.
+	// A name set with WithCircuitName takes precedence.
+	Key func(req *http.Request) string
+	// IsFailure decides whether an outcome counts as a failure. By default transport
+	// errors (other than context cancellation), 5xx and 429 responses do.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called whenever a circuit changes state, e.g. for logging or metrics.
+	// It runs with the breaker locked and must not call back into it.
+	OnStateChange func(key string, from CircuitState, to CircuitState)
+}
+
+// CircuitBreaker stops sending requests to a downstream that keeps failing.
+// Each key (host by default) has its own circuit: it opens when the failure rate
+// crosses FailureRate, rejects requests with ErrCircuitOpen for Cooldown, then
+// lets HalfOpenProbes requests through and closes again if they succeed.
+type CircuitBreaker struct {
+	cfg      BreakerConfig
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// breakerBuckets is the number of slices the rolling window is divided into
+const breakerBuckets = 10
+
+// circuit holds the state of one key, guarded by CircuitBreaker.mu
+type circuit struct {
+	state     CircuitState
+	openedAt  time.Time
+	buckets   [breakerBuckets]breakerBucket
+	probes    int // probes in flight while half-open
+	succeeded int // successful probes while half-open
+}
+
+type breakerBucket struct {
+	start    time.Time
+	requests int
+	failures int
+}
+
+// NewCircuitBreaker creates a CircuitBreaker.
+//
+// Example usage:
+//
+//	breaker := NewCircuitBreaker(BreakerConfig{FailureRate: 0.5, MinRequests: 20, Cooldown: 15 * time.Second})
+//	client := NewClient(WithBaseURL("https://api.example.com"), WithCircuitBreaker(breaker))
+//
+//	err := Get(client, "/orders", &orders, nil, nil)
+//	if errors.Is(err, ErrCircuitOpen) {
+//		// fail fast, serve a fallback
+//	}
+//
+//	// health check
+//	for key, state := range breaker.States() {
+//		log.Printf("%s: %s", key, state)
+//	}
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	// the window is split into buckets at least a nanosecond wide
+	cfg.Window = max(cfg.Window, breakerBuckets)
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = defaultIsFailure
+	}
+	return &CircuitBreaker{cfg: cfg, circuits: make(map[string]*circuit)}
+}
+
+// WithCircuitBreaker guards the client's requests with breaker. It runs inside
+// the retry loop, so every attempt is counted and retries stop once the circuit opens.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return WithMiddleware(breaker.Middleware())
+}
+
+type circuitNameKey struct{}
+
+// WithCircuitName returns a context whose requests use the circuit called name
+// instead of the one picked by BreakerConfig.Key, e.g. to give a slow endpoint its own circuit.
+//
+// Example usage:
+//
+//	ctx := WithCircuitName(ctx, "search")
+//	err := GetCtx(ctx, client, "/search", &results, params, nil)
+func WithCircuitName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, circuitNameKey{}, name)
+}
+
+// Middleware returns the breaker as a Middleware, for use with WithMiddleware or UseMiddleware.
+func (b *CircuitBreaker) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := b.key(req)
+			probe, err := b.allow(key)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if errors.Is(err, context.Canceled) {
+				b.abandon(key, probe)
+			} else {
+				b.record(key, probe, b.cfg.IsFailure(resp, err))
+			}
+			return resp, err
+		})
+	}
+}
+
+// State returns the current state of the circuit for key. Unknown keys are closed.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		return CircuitClosed
+	}
+	b.advance(key, c, time.Now())
+	return c.state
+}
+
+// States returns the state of every circuit that has seen a request.
+func (b *CircuitBreaker) States() map[string]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	states := make(map[string]CircuitState, len(b.circuits))
+	for key, c := range b.circuits {
+		b.advance(key, c, now)
+		states[key] = c.state
+	}
+	return states
+}
+
+// Healthy reports whether no circuit is open.
+func (b *CircuitBreaker) Healthy() bool {
+	for _, state := range b.States() {
+		if state == CircuitOpen {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset closes the circuit for key and forgets its history.
+func (b *CircuitBreaker) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[key]; ok {
+		b.transition(key, c, CircuitClosed, time.Now())
+	}
+}
+
+func (b *CircuitBreaker) key(req *http.Request) string {
+	if name, ok := req.Context().Value(circuitNameKey{}).(string); ok && name != "" {
+		return name
+	}
+	if b.cfg.Key != nil {
+		return b.cfg.Key(req)
+	}
+	return req.URL.Host
+}
+
+// allow admits a request or returns a *CircuitOpenError. probe is true when the
+// request was admitted as a half-open probe.
+func (b *CircuitBreaker) allow(key string) (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	b.advance(key, c, now)
+
+	switch c.state {
+	case CircuitOpen:
+		return false, &CircuitOpenError{Key: key, RetryAfter: c.openedAt.Add(b.cfg.Cooldown).Sub(now)}
+	case CircuitHalfOpen:
+		if c.probes+c.succeeded >= b.cfg.HalfOpenProbes {
+			return false, &CircuitOpenError{Key: key}
+		}
+		c.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of an admitted request
+func (b *CircuitBreaker) record(key string, probe bool, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	c := b.circuits[key]
+
+	if probe {
+		if c.state != CircuitHalfOpen {
+			return
+		}
+		c.probes--
+		if failed {
+			b.transition(key, c, CircuitOpen, now)
+			return
+		}
+		c.succeeded++
+		if c.succeeded >= b.cfg.HalfOpenProbes {
+			b.transition(key, c, CircuitClosed, now)
+		}
+		return
+	}
+	if c.state != CircuitClosed {
+		// a request admitted before the circuit opened
+		return
+	}
+
+	bucket := c.bucket(now, b.cfg.Window)
+	bucket.requests++
+	if failed {
+		bucket.failures++
+	}
+
+	requests, failures := c.totals(now, b.cfg.Window)
+	if requests >= b.cfg.MinRequests && float64(failures)/float64(requests) >= b.cfg.FailureRate {
+		b.transition(key, c, CircuitOpen, now)
+	}
+}
+
+// abandon releases a probe slot without counting a request the caller cancelled
+func (b *CircuitBreaker) abandon(key string, probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuits[key]; c.state == CircuitHalfOpen {
+		c.probes--
+	}
+}
+
+// advance moves an open circuit to half-open once its cooldown has passed
+func (b *CircuitBreaker) advance(key string, c *circuit, now time.Time) {
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= b.cfg.Cooldown {
+		b.transition(key, c, CircuitHalfOpen, now)
+	}
+}
+
+func (b *CircuitBreaker) transition(key string, c *circuit, to CircuitState, now time.Time) {
+	from := c.state
+	c.state = to
+	c.probes = 0
+	c.succeeded = 0
+	switch to {
+	case CircuitOpen:
+		c.openedAt = now
+	case CircuitClosed:
+		c.buckets = [breakerBuckets]breakerBucket{}
+	}
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(key, from, to)
+	}
+}
+
+// bucket returns the bucket for now, recycling it if it belongs to an earlier window
+func (c *circuit) bucket(now time.Time, window time.Duration) *breakerBucket {
+	width := window / breakerBuckets
+	start := now.Truncate(width)
+	bucket := &c.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = breakerBucket{start: start}
+	}
+	return bucket
+}
+
+// totals sums the buckets inside the window ending at now
+func (c *circuit) totals(now time.Time, window time.Duration) (requests int, failures int) {
+	for _, bucket := range c.buckets {
+		if now.Sub(bucket.start) < window {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return requests, failures
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
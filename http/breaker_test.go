@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndFailsFast(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	breaker := NewCircuitBreaker(BreakerConfig{MinRequests: 3, Cooldown: time.Hour})
+	client := NewClient(WithCircuitBreaker(breaker))
+	for range 3 {
+		Get[any](client, srv.URL, nil, nil, nil)
+	}
+
+	err := Get[any](client, srv.URL, nil, nil, nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if calls != 3 {
+		t.Errorf("server called %d times, want 3 before the circuit opened", calls)
+	}
+}
+
+func TestCircuitBreakerTinyWindow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// a window narrower than one nanosecond per bucket used to divide by zero
+	for _, window := range []time.Duration{time.Nanosecond, 9 * time.Nanosecond} {
+		client := NewClient(WithCircuitBreaker(NewCircuitBreaker(BreakerConfig{Window: window})))
+		if err := Get[any](client, srv.URL, nil, nil, nil); err != nil {
+			t.Errorf("Window %v: %v", window, err)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheEntry is a stored GET response.
+type CacheEntry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Vary       http.Header `json:"vary,omitempty"` // request header values the response varies on
+	StoredAt   time.Time   `json:"stored_at"`
+	Expires    time.Time   `json:"expires"`
+}
+
+// CacheStore is a backend for Cache. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// CacheStats counts how cached requests were served.
+type CacheStats struct {
+	Hits          int64 // served from the cache without contacting the server
+	Revalidations int64 // served from the cache after a 304 Not Modified
+	Misses        int64 // fetched from the server
+}
+
+// Cache is an HTTP cache for GET responses that honours Cache-Control, Expires,
+// ETag and Last-Modified. Stale entries with validators are revalidated with
+// If-None-Match / If-Modified-Since and served from the cache on 304.
+// Responses served from the cache carry an "X-Cache: HIT" header, others "X-Cache: MISS".
+type Cache struct {
+	store         CacheStore
+	hits          atomic.Int64
+	revalidations atomic.Int64
+	misses        atomic.Int64
+}
+
+// NewCache creates a Cache backed by store.
+//
+// Example usage:
+//
+//	cache := NewCache(NewMemoryCache(1000))
+//	client := NewClient(WithBaseURL("https://api.example.com"), WithCache(cache))
+//
+//	var countries []Country
+//	err := Get(client, "/reference/countries", &countries, nil, nil)
+//
+//	stats := cache.Stats()
+//	log.Printf("cache hits=%d misses=%d", stats.Hits+stats.Revalidations, stats.Misses)
+func NewCache(store CacheStore) *Cache {
+	return &Cache{store: store}
+}
+
+// WithCache caches the client's GET responses in cache. The cache runs inside all middleware,
+// per-request middleware included, whatever order the options are given in, so it sees the
+// Authorization header set by WithTokenSource, BearerAuth, etc. and keeps callers apart.
+func WithCache(cache *Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// Stats returns the number of hits, revalidations and misses so far.
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:          c.hits.Load(),
+		Revalidations: c.revalidations.Load(),
+		Misses:        c.misses.Load(),
+	}
+}
+
+// Middleware returns the cache as a Middleware, for use with WithMiddleware or UseMiddleware.
+// Responses are cached per Authorization header, so it must come after any auth middleware;
+// WithCache takes care of that.
+func (c *Cache) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return c.roundTrip(next, req)
+		})
+	}
+}
+
+type skipCacheKey struct{}
+
+// skipCache returns a context whose requests bypass the cache, for streamed responses
+// that must not be read into memory to be stored
+func skipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func (c *Cache) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	reqDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+	if req.Method != http.MethodGet || reqDirectives.has("no-store") || req.Context().Value(skipCacheKey{}) != nil {
+		return next.RoundTrip(req)
+	}
+
+	key := cacheKey(req, c.varyNames(req))
+	entry, ok := c.store.Get(key)
+	if ok && !entry.matches(req) {
+		ok = false
+	}
+
+	// fresh enough to serve without asking the server
+	if ok && !reqDirectives.has("no-cache") && time.Now().Before(entry.Expires) {
+		c.hits.Add(1)
+		return entry.response(req, "HIT"), nil
+	}
+
+	// stale, ask the server whether it changed
+	outReq := req
+	if ok {
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		// refresh headers and freshness from the 304
+		for key, values := range resp.Header {
+			entry.Header[key] = values
+		}
+		entry.StoredAt = time.Now()
+		entry.Expires = expiresAt(entry.Header, entry.StoredAt)
+		c.store.Set(key, entry)
+		c.revalidations.Add(1)
+		return entry.response(req, "HIT"), nil
+	}
+
+	c.misses.Add(1)
+	resp.Header.Set("X-Cache", "MISS")
+	if !storable(resp) {
+		if ok {
+			c.store.Delete(key)
+		}
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	stored := &CacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Vary:       varyValues(req, resp.Header),
+		StoredAt:   now,
+		Expires:    expiresAt(resp.Header, now),
+	}
+	stored.Header.Del("X-Cache")
+
+	// the key may have to include the request headers this response varies on
+	names := responseVary(resp.Header)
+	if len(names) > 0 {
+		c.store.Set(varyIndexKey(req), &CacheEntry{Vary: http.Header{"Vary": names}, StoredAt: now})
+	}
+	if storedKey := cacheKey(req, names); storedKey != key {
+		if ok {
+			c.store.Delete(key)
+		}
+		key = storedKey
+	}
+	c.store.Set(key, stored)
+	return resp, nil
+}
+
+// cacheKey is the URL plus a digest of the request's credentials and the values of the
+// named headers, so variants of a response (one per user, say) are stored side by side
+func cacheKey(req *http.Request, names []string) string {
+	digest := sha256.New()
+	for _, name := range append([]string{"Authorization"}, names...) {
+		fmt.Fprintf(digest, "%s: %s\n", http.CanonicalHeaderKey(name), strings.Join(req.Header.Values(name), ","))
+	}
+	return req.URL.String() + " " + hex.EncodeToString(digest.Sum(nil))
+}
+
+// varyIndexKey stores the Vary header names last seen for a URL
+func varyIndexKey(req *http.Request) string {
+	return req.URL.String() + " vary"
+}
+
+// varyNames returns the Vary header names recorded for the request's URL
+func (c *Cache) varyNames(req *http.Request) []string {
+	index, ok := c.store.Get(varyIndexKey(req))
+	if !ok {
+		return nil
+	}
+	return index.Vary.Values("Vary")
+}
+
+// responseVary lists the header names in a response's Vary header, sorted and without duplicates
+func responseVary(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = http.CanonicalHeaderKey(strings.TrimSpace(name)); name != "" && name != "Authorization" {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// response rebuilds an *http.Response from the entry
+func (e *CacheEntry) response(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-Cache", status)
+	header.Set("Age", strconv.Itoa(int(time.Since(e.StoredAt).Seconds())))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// matches reports whether the request has the same values for the headers the response varies on
+func (e *CacheEntry) matches(req *http.Request) bool {
+	for key, values := range e.Vary {
+		if strings.Join(req.Header.Values(key), ",") != strings.Join(values, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func varyValues(req *http.Request, header http.Header) http.Header {
+	vary := make(http.Header)
+	// always vary on credentials so one caller never sees another's data
+	for _, name := range append([]string{"Authorization"}, responseVary(header)...) {
+		vary[name] = req.Header.Values(name)
+	}
+	return vary
+}
+
+// storable reports whether a response may be cached
+func storable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	directives := parseCacheControl(resp.Header.Get("Cache-Control"))
+	if directives.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	// worth keeping only if it is fresh for a while or can be revalidated
+	return resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" ||
+		expiresAt(resp.Header, time.Now()).After(time.Now())
+}
+
+// expiresAt works out when a response stops being fresh
+func expiresAt(header http.Header, storedAt time.Time) time.Time {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if directives.has("no-cache") {
+		return storedAt
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			return storedAt.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		expiry, err := http.ParseTime(expires)
+		if err != nil {
+			return storedAt
+		}
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			// use the server's clock for the lifetime
+			return storedAt.Add(expiry.Sub(date))
+		}
+		return expiry
+	}
+	return storedAt
+}
+
+type cacheControl map[string]string
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+func parseCacheControl(value string) cacheControl {
+	cc := make(cacheControl)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, _ := strings.Cut(part, "=")
+		cc[strings.ToLower(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(val), `"`)
+	}
+	return cc
+}
+
+// MemoryCache is an in-memory CacheStore that evicts the least recently used entry once full.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	order      *list.List
+	items      map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewMemoryCache creates an LRU CacheStore holding up to maxEntries responses (0 for no limit).
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (m *MemoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	// hand out a copy so callers can't change what's stored
+	entry := *elem.Value.(*memoryItem).entry
+	entry.Header = entry.Header.Clone()
+	return &entry, true
+}
+
+func (m *MemoryCache) Set(key string, entry *CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		elem.Value.(*memoryItem).entry = entry
+		m.order.MoveToFront(elem)
+		return
+	}
+
+	m.items[key] = m.order.PushFront(&memoryItem{key: key, entry: entry})
+	if m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		m.order.Remove(elem)
+		delete(m.items, key)
+	}
+}
+
+// DiskCache is a CacheStore that keeps one JSON file per response in a directory,
+// so cached data survives restarts.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a DiskCache in dir, creating the directory if needed.
+//
+// Example usage:
+//
+//	store, err := NewDiskCache(filepath.Join(os.TempDir(), "goutils-http-cache"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	client := NewClient(WithCache(NewCache(store)))
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("Error Creating Cache Directory (%s): %w", dir, err)
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (d *DiskCache) Get(key string) (*CacheEntry, bool) {
+	file, err := os.Open(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var entry CacheEntry
+	if err := json.NewDecoder(bufio.NewReader(file)).Decode(&entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (d *DiskCache) Set(key string, entry *CacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// write then rename so readers never see a partial file
+	tmp, err := os.CreateTemp(d.dir, "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	err = errors.Join(err, tmp.Close())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (d *DiskCache) Delete(key string) {
+	os.Remove(d.path(key))
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// perCallerServer answers with the caller's Authorization and Accept-Language, cacheable for an hour
+func perCallerServer(t *testing.T, vary string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=3600")
+		if vary != "" {
+			w.Header().Set("Vary", vary)
+		}
+		fmt.Fprintf(w, "%s %s", r.Header.Get("Authorization"), r.Header.Get("Accept-Language"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestCacheKeepsCallersApart(t *testing.T) {
+	srv, calls := perCallerServer(t, "")
+	client := NewClient(WithCache(NewCache(NewMemoryCache(100))))
+
+	// alternate callers; each should be fetched once and then served from the cache
+	for range 3 {
+		for _, token := range []string{"alice", "bob"} {
+			var got string
+			headers := map[string]string{"Authorization": "Bearer " + token}
+			if err := Get(client, srv.URL, &got, nil, headers); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got != "Bearer "+token+" " {
+				t.Fatalf("%s got %q", token, got)
+			}
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("server called %d times, want once per caller", calls.Load())
+	}
+}
+
+func TestCacheSeesTokenSourceWhateverTheOptionOrder(t *testing.T) {
+	srv, calls := perCallerServer(t, "")
+	cache := NewCache(NewMemoryCache(100))
+	alice := NewClient(WithCache(cache), WithTokenSource(StaticToken("alice")))
+	bob := NewClient(WithCache(cache), WithTokenSource(StaticToken("bob")))
+
+	for _, tt := range []struct {
+		client *Client
+		want   string
+	}{{alice, "Bearer alice "}, {bob, "Bearer bob "}, {alice, "Bearer alice "}, {bob, "Bearer bob "}} {
+		var got string
+		if err := Get(tt.client, srv.URL, &got, nil, nil); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("got %q, want %q", got, tt.want)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("server called %d times, want 2", calls.Load())
+	}
+}
+
+func TestCacheStoresVariantsSideBySide(t *testing.T) {
+	srv, calls := perCallerServer(t, "Accept-Language")
+	cache := NewCache(NewMemoryCache(100))
+	client := NewClient(WithCache(cache))
+
+	for range 3 {
+		for _, lang := range []string{"en", "de"} {
+			var got string
+			if err := Get(client, srv.URL, &got, nil, map[string]string{"Accept-Language": lang}); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got != " "+lang {
+				t.Fatalf("got %q for %s", got, lang)
+			}
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("server called %d times, want once per language", calls.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 4 || stats.Misses != 2 {
+		t.Fatalf("stats %+v", stats)
+	}
+}
@@ -0,0 +1,385 @@
+package http
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrUnmatchedRequest is returned by a Replayer for requests that aren't in its cassette.
+var ErrUnmatchedRequest = errors.New("no recorded interaction matches the request")
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request half of an Interaction.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Base64 bool        `json:"base64,omitempty"` // Body is base64 encoded binary
+}
+
+// RecordedResponse is the response half of an Interaction.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Base64     bool        `json:"base64,omitempty"` // Body is base64 encoded binary
+}
+
+// Cassette is the JSON file a Recorder writes and a Replayer reads.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error Reading Cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("Error Unmarshaling Cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path as indented JSON, creating parent directories.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Error Marshaling Cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("Error Writing Cassette: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("Error Writing Cassette: %w", err)
+	}
+	return nil
+}
+
+// CassetteOptions controls what is masked in a cassette and how requests are matched.
+// Redaction is applied before recording and to incoming requests before matching,
+// so a redacted secret still matches on replay. With RedactFields set, JSON and form
+// bodies that can't be parsed are recorded as a placeholder.
+type CassetteOptions struct {
+	RedactHeaders []string // headers to mask, defaults to Authorization, Cookie, Set-Cookie, X-Api-Key, Proxy-Authorization
+	RedactParams  []string // query parameters to mask, e.g. "api_key"
+	RedactFields  []string // JSON or form fields to mask anywhere in request and response bodies
+
+	// Match decides which recorded request answers an incoming one, defaults to
+	// MatchAll(MatchMethod, MatchURL).
+	Match Matcher
+	// AllowRepeats lets a replayed interaction answer again once every match has been used.
+	// Otherwise each interaction is served once, in recorded order.
+	AllowRepeats bool
+}
+
+// Matcher reports whether an incoming request (already redacted) matches a recorded one.
+type Matcher func(req RecordedRequest, recorded RecordedRequest) bool
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(req RecordedRequest, recorded RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches requests with the same URL, ignoring query parameter order.
+func MatchURL(req RecordedRequest, recorded RecordedRequest) bool {
+	a, errA := url.Parse(req.URL)
+	b, errB := url.Parse(recorded.URL)
+	if errA != nil || errB != nil {
+		return req.URL == recorded.URL
+	}
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Path == b.Path && reflect.DeepEqual(a.Query(), b.Query())
+}
+
+// MatchPath matches requests with the same path, ignoring host and query.
+func MatchPath(req RecordedRequest, recorded RecordedRequest) bool {
+	a, errA := url.Parse(req.URL)
+	b, errB := url.Parse(recorded.URL)
+	return errA == nil && errB == nil && a.Path == b.Path
+}
+
+// MatchBody matches requests with the same body. JSON bodies are compared by value,
+// so key order and whitespace don't matter.
+func MatchBody(req RecordedRequest, recorded RecordedRequest) bool {
+	if req.Body == recorded.Body {
+		return true
+	}
+	var a, b any
+	if json.Unmarshal([]byte(req.Body), &a) != nil || json.Unmarshal([]byte(recorded.Body), &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// MatchAll combines matchers, matching when all of them do.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(req RecordedRequest, recorded RecordedRequest) bool {
+		for _, match := range matchers {
+			if !match(req, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func (o *CassetteOptions) defaults() {
+	if o.RedactHeaders == nil {
+		o.RedactHeaders = defaultRedactHeaders
+	}
+	if o.Match == nil {
+		o.Match = MatchAll(MatchMethod, MatchURL)
+	}
+}
+
+// Recorder is a RoundTripper that sends requests through another RoundTripper and
+// appends every exchange to a cassette file, rewriting it after each one.
+type Recorder struct {
+	path     string
+	next     http.RoundTripper
+	opts     CassetteOptions
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records the requests sent through next (http.DefaultTransport if nil) to
+// the cassette at path, replacing any existing recording.
+//
+// Example usage:
+//
+//	// record once against the real API
+//	recorder := NewRecorder("testdata/orders.json", nil, CassetteOptions{RedactParams: []string{"api_key"}})
+//	client := NewClient(WithTransport(recorder))
+func NewRecorder(path string, next http.RoundTripper, opts CassetteOptions) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	opts.defaults()
+	return &Recorder{path: path, next: next, opts: opts}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Error Recording Response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{Request: r.opts.recordRequest(req, reqBody)}
+	interaction.Response.StatusCode = resp.StatusCode
+	interaction.Response.Header = r.opts.redactHeader(resp.Header)
+	interaction.Response.Body, interaction.Response.Base64 = r.opts.recordBody(resp.Header.Get("Content-Type"), respBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Replayer is a RoundTripper that answers requests from a cassette without
+// touching the network. Requests that match no recorded interaction fail with
+// ErrUnmatchedRequest.
+type Replayer struct {
+	opts     CassetteOptions
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer serves the interactions recorded in the cassette at path.
+//
+// Example usage:
+//
+//	// in tests, no network needed
+//	replayer, err := NewReplayer("testdata/orders.json", CassetteOptions{
+//		Match: MatchAll(MatchMethod, MatchURL, MatchBody),
+//	})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	DefaultClient = NewClient(WithTransport(replayer)) // also covers MakeRequest and chatgpt.SendRequest
+func NewReplayer(path string, opts CassetteOptions) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	opts.defaults()
+	return &Replayer{opts: opts, cassette: cassette, used: make([]bool, len(cassette.Interactions))}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	incoming := r.opts.recordRequest(req, reqBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.opts.Match(incoming, interaction.Request) {
+			continue
+		}
+		if !r.used[i] {
+			found = i
+			break
+		}
+		if r.opts.AllowRepeats && found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrUnmatchedRequest, incoming.Method, incoming.URL)
+	}
+	r.used[found] = true
+
+	recorded := r.cassette.Interactions[found].Response
+	body, err := decodeRecordedBody(recorded.Body, recorded.Base64)
+	if err != nil {
+		return nil, fmt.Errorf("Error Replaying Response: %w", err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the recorded interactions that haven't been replayed, so tests can
+// check that every expected call was made.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// RecordOrReplay replays the cassette at path if it exists and records to it through
+// next otherwise, so the first test run captures real traffic and later runs are offline.
+// Delete the file to record again.
+func RecordOrReplay(path string, next http.RoundTripper, opts CassetteOptions) (http.RoundTripper, error) {
+	if _, err := os.Stat(path); err == nil {
+		return NewReplayer(path, opts)
+	}
+	return NewRecorder(path, next, opts), nil
+}
+
+// readRequestBody reads the request body and puts it back so it can still be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Error Reading Request Body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// recordRequest builds the redacted form of req that is stored and matched on
+func (o *CassetteOptions) recordRequest(req *http.Request, body []byte) RecordedRequest {
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    o.redactParams(req.URL),
+		Header: o.redactHeader(req.Header),
+	}
+	recorded.Body, recorded.Base64 = o.recordBody(req.Header.Get("Content-Type"), body)
+	return recorded
+}
+
+// recordBody redacts a body for the cassette. A JSON or form body that can't be parsed,
+// such as NDJSON sent as application/json, is replaced by a placeholder rather than
+// recorded with its fields intact.
+func (o *CassetteOptions) recordBody(contentType string, body []byte) (string, bool) {
+	clean, ok := redactBody(contentType, body, o.RedactFields)
+	if !ok {
+		clean = fmt.Appendf(nil, "%s (%d byte body that could not be parsed for redaction)", redacted, len(body))
+	}
+	return encodeRecordedBody(clean)
+}
+
+func (o *CassetteOptions) redactHeader(header http.Header) http.Header {
+	clean := header.Clone()
+	for key := range clean {
+		if containsFold(o.RedactHeaders, key) {
+			clean[key] = []string{redacted}
+		}
+	}
+	return clean
+}
+
+func (o *CassetteOptions) redactParams(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = redactQuery(clean.RawQuery, o.RedactParams)
+	return clean.String()
+}
+
+// encodeRecordedBody stores text bodies as-is and binary bodies as base64
+func encodeRecordedBody(body []byte) (string, bool) {
+	if utf8.Valid(body) {
+		return string(body), false
+	}
+	return base64.StdEncoding.EncodeToString(body), true
+}
+
+func decodeRecordedBody(body string, isBase64 bool) ([]byte, error) {
+	if isBase64 {
+		return base64.StdEncoding.DecodeString(body)
+	}
+	return []byte(body), nil
+}
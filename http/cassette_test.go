@@ -0,0 +1,211 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type cassetteOrder struct {
+	ID    string `json:"id"`
+	Token string `json:"token,omitempty"`
+}
+
+// recordCassette records a JSON order request and a binary download against a live server
+func recordCassette(t *testing.T, path string, opts CassetteOptions) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id":"ord_1","token":"tok_live","echo":%s}`, body)
+		case "/download":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithTransport(NewRecorder(path, nil, opts)))
+	var order cassetteOrder
+	err := Post(client, "/orders", &order, map[string]string{"item": "book", "token": "tok_secret"},
+		map[string]string{"api_key": "key_secret"}, map[string]string{"Authorization": "Bearer live"})
+	if err != nil {
+		t.Fatalf("recording Post() error = %v", err)
+	}
+	if order.Token != "tok_live" {
+		t.Fatalf("recording returned token %q, the live response must not be redacted", order.Token)
+	}
+	var download []byte
+	if err := Get(client, "/download", &download, nil, nil); err != nil {
+		t.Fatalf("recording Get() error = %v", err)
+	}
+	return server.URL
+}
+
+func TestCassetteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "orders.json")
+	opts := CassetteOptions{RedactParams: []string{"api_key"}, RedactFields: []string{"token"}}
+	baseURL := recordCassette(t, path, opts)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"Bearer live", "key_secret", "tok_secret", "tok_live"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+
+	// the server is gone, every answer comes from the cassette
+	replayer, err := NewReplayer(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(WithBaseURL(baseURL), WithTransport(replayer))
+
+	var order cassetteOrder
+	err = Post(client, "/orders", &order, map[string]string{"token": "tok_other", "item": "book"},
+		map[string]string{"api_key": "other_key"}, map[string]string{"Authorization": "Bearer other"})
+	if err != nil {
+		t.Fatalf("replayed Post() error = %v", err)
+	}
+	if order.ID != "ord_1" || order.Token != redacted {
+		t.Errorf("replayed order = %+v, want ord_1 with a redacted token", order)
+	}
+	if unused := replayer.Unused(); len(unused) != 1 || !strings.HasSuffix(unused[0].Request.URL, "/download") {
+		t.Errorf("Unused() = %+v, want only the download", unused)
+	}
+
+	var download []byte
+	if err := Get(client, "/download", &download, nil, nil); err != nil {
+		t.Fatalf("replayed Get() error = %v", err)
+	}
+	if string(download) != "\xff\x00\xfe" {
+		t.Errorf("replayed binary body = %x, want ff00fe", download)
+	}
+
+	// each interaction answers once without AllowRepeats
+	err = Get(client, "/download", &download, nil, nil)
+	if !errors.Is(err, ErrUnmatchedRequest) {
+		t.Errorf("second replay error = %v, want ErrUnmatchedRequest", err)
+	}
+	err = Get(client, "/customers", &download, nil, nil)
+	if !errors.Is(err, ErrUnmatchedRequest) {
+		t.Errorf("unrecorded request error = %v, want ErrUnmatchedRequest", err)
+	}
+}
+
+func TestCassetteMasksUnparseableBodies(t *testing.T) {
+	// NDJSON labelled as JSON can't be parsed, so its fields can't be redacted one by one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{\"token\":\"tok_response\"}\n{\"token\":\"tok_response\"}\n")
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "ndjson.json")
+	opts := CassetteOptions{RedactFields: []string{"token"}}
+	client := NewClient(WithTransport(NewRecorder(path, nil, opts)))
+	body := RawBody(strings.NewReader(`{"token":"tok_request"`), "application/json")
+	var raw []byte
+	if err := Post(client, server.URL+"/events", &raw, body, nil, nil); err != nil {
+		t.Fatalf("recording Post() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"tok_request", "tok_response"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "could not be parsed for redaction") {
+		t.Errorf("cassette has no placeholder for the unparseable bodies:\n%s", data)
+	}
+
+	// the same unparseable request still matches on replay
+	replayer, err := NewReplayer(path, CassetteOptions{RedactFields: opts.RedactFields, Match: MatchAll(MatchMethod, MatchURL, MatchBody)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = NewClient(WithTransport(replayer))
+	body = RawBody(strings.NewReader(`{"token":"tok_request"`), "application/json")
+	if err := Post(client, server.URL+"/events", &raw, body, nil, nil); err != nil {
+		t.Errorf("replayed Post() error = %v", err)
+	}
+}
+
+func TestReplayerMatching(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	cassette := Cassette{Interactions: []Interaction{
+		{
+			Request:  RecordedRequest{Method: "POST", URL: "https://api.example.com/orders?b=2&a=1", Body: `{"item":"book","qty":1}`},
+			Response: RecordedResponse{StatusCode: 201, Body: "book"},
+		},
+		{
+			Request:  RecordedRequest{Method: "POST", URL: "https://api.example.com/orders?b=2&a=1", Body: `{"item":"pen","qty":1}`},
+			Response: RecordedResponse{StatusCode: 201, Body: "pen"},
+		},
+	}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := NewReplayer(path, CassetteOptions{Match: MatchAll(MatchMethod, MatchURL, MatchBody), AllowRepeats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(WithTransport(replayer))
+
+	// query order, key order and whitespace don't matter
+	for range 2 {
+		var got string
+		err := Post(client, "https://api.example.com/orders", &got, map[string]any{"qty": 1, "item": "pen"}, map[string]string{"a": "1", "b": "2"}, nil)
+		if err != nil || got != "pen" {
+			t.Errorf("Post() = %q, %v, want pen", got, err)
+		}
+	}
+
+	var got string
+	err = Post(client, "https://api.example.com/orders", &got, map[string]any{"item": "cup", "qty": 1}, map[string]string{"a": "1", "b": "2"}, nil)
+	if !errors.Is(err, ErrUnmatchedRequest) {
+		t.Errorf("unmatched body error = %v, want ErrUnmatchedRequest", err)
+	}
+}
+
+func TestRecordOrReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ping.json")
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, "pong")
+	}))
+	defer server.Close()
+
+	for range 2 {
+		transport, err := RecordOrReplay(path, nil, CassetteOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if err := Get(NewClient(WithTransport(transport)), server.URL+"/ping", &got, nil, nil); err != nil || got != "pong" {
+			t.Fatalf("Get() = %q, %v, want pong", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("server called %d times, want 1 (recorded, then replayed)", calls)
+	}
+}
@@ -0,0 +1,355 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client is a reusable HTTP client that holds connection state and request
+// defaults (base URL, headers, timeout, transport) shared across calls.
+// A Client is safe for concurrent use and should be created once and reused.
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	headers    map[string]string
+	transport  http.RoundTripper
+	tlsConfig  *tls.Config
+	proxy      *url.URL
+	timeout    time.Duration
+	retry      *RetryPolicy
+	decoders   map[string]Decoder
+	middleware []Middleware
+	cache      *Cache
+}
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+// RequestOption configures a single request sent with Do, Get, Post, etc.
+type RequestOption func(*requestConfig)
+
+// requestConfig holds per-request settings collected from RequestOptions
+type requestConfig struct {
+	decoder    Decoder
+	middleware []Middleware
+	streamPath string
+	ndjson     bool
+}
+
+func newRequestConfig(opts []RequestOption) *requestConfig {
+	cfg := &requestConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// DefaultClient is the Client used by MakeRequest and MakeGraphQLRequest.
+var DefaultClient = NewClient()
+
+// NewClient creates a Client with a 30 second timeout and applies the given options.
+//
+// Parameters:
+//   - opts: Functional options (WithBaseURL, WithHeaders, WithTimeout, WithTransport, WithTLSConfig, WithProxy, WithRetry, WithContentDecoder, WithMiddleware)
+//
+// Returns:
+//   - *Client: The configured client
+//
+// Example usage:
+//
+//	client := NewClient(
+//		WithBaseURL("https://api.example.com/v1"),
+//		WithHeaders(map[string]string{"Authorization": "Bearer token123"}),
+//		WithTimeout(10*time.Second),
+//	)
+//
+//	var user User
+//	err := Get(client, "/users/1", &user, nil, nil)
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		headers: make(map[string]string),
+		timeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// the cache sits innermost so it sees the headers every middleware has set
+	transport := c.buildTransport()
+	if c.cache != nil {
+		transport = c.cache.Middleware()(transport)
+	}
+	c.httpClient = &http.Client{
+		Transport: chain(perRequestTransport{next: transport}, c.middleware),
+		Timeout:   c.timeout,
+	}
+	return c
+}
+
+// WithBaseURL sets a base URL that is prepended to relative request URLs.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHeaders sets headers that are sent with every request. Headers passed
+// to an individual request take precedence over these defaults.
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Client) {
+		for key, value := range headers {
+			c.headers[key] = value
+		}
+	}
+}
+
+// WithTimeout sets the overall timeout for a single request, including reading the response body.
+// A timeout of zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithTransport sets the underlying http.RoundTripper used to send requests.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used by the client's transport.
+// It only applies when the transport is an *http.Transport (the default).
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		c.tlsConfig = cfg
+	}
+}
+
+// WithProxy routes every request through the given proxy URL.
+// It only applies when the transport is an *http.Transport (the default).
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *Client) {
+		c.proxy = proxyURL
+	}
+}
+
+// buildTransport returns the configured transport with TLS and proxy settings applied
+func (c *Client) buildTransport() http.RoundTripper {
+	transport := c.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if c.tlsConfig == nil && c.proxy == nil {
+		return transport
+	}
+
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return transport
+	}
+	t := base.Clone()
+	if c.tlsConfig != nil {
+		t.TLSClientConfig = c.tlsConfig
+	}
+	if c.proxy != nil {
+		t.Proxy = http.ProxyURL(c.proxy)
+	}
+	return t
+}
+
+// resolveURL joins relative request URLs onto the client's base URL
+func (c *Client) resolveURL(rawURL string) string {
+	if c.baseURL == "" || strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://") {
+		return rawURL
+	}
+	return c.baseURL + "/" + strings.TrimLeft(rawURL, "/")
+}
+
+// Do sends an HTTP request using the client and decodes the response into res.
+// It is equivalent to DoCtx with context.Background().
+//
+// Parameters:
+//   - c: The client to send the request with
+//   - method: HTTP method (GET, POST, PUT, PATCH, DELETE, etc.)
+//   - url: The target URL, absolute or relative to the client's base URL
+//   - res: Pointer to the value the response is decoded into, nil to discard it
+//   - body: Request body, pass nil for GET requests. A Body (FormBody, MultipartBody, XMLBody, RawBody)
+//     is encoded as such, any other value is JSON marshaled
+//   - params: Query parameters as key-value pairs
+//   - headers: HTTP headers as key-value pairs, merged over the client's default headers
+//   - opts: Per-request options such as DecodeWith or UseMiddleware
+//
+// The response decoder is chosen by DecodeWith if given, then by res (*[]byte receives the raw body, as
+// does *string unless the response is JSON), then by the response Content-Type (JSON, XML, or
+// WithContentDecoder), falling back to JSON.
+//
+// Returns an error if the request fails, status code is not 2xx (as *HTTPError), or decoding fails.
+//
+// Example usage:
+//
+//	client := NewClient(WithBaseURL("https://api.example.com"))
+//
+//	var user User
+//	err := Do(client, "PUT", "/users/1", &user, User{Name: "John"}, nil, nil)
+//
+//	// form post, plain text response
+//	var ack string
+//	err = Do(client, "POST", "/subscribe", &ack, FormBody(url.Values{"email": {"john@example.com"}}), nil, nil)
+func Do[T any](c *Client, method string, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return DoCtx(context.Background(), c, method, url, res, body, params, headers, opts...)
+}
+
+// DoCtx is like Do but carries ctx through the whole request, including reading the response body.
+// Cancelling ctx or reaching its deadline aborts the in-flight request and returns ctx's error.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//
+//	var user User
+//	err := DoCtx(ctx, client, "GET", "/users/1", &user, nil, nil, nil)
+//	if errors.Is(err, context.DeadlineExceeded) {
+//		// request took too long
+//	}
+func DoCtx[T any](ctx context.Context, c *Client, method string, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	cfg := newRequestConfig(opts)
+	ctx = withRequestMiddleware(ctx, cfg.middleware)
+
+	req, err := c.newRequest(ctx, method, url, body, params, headers)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.execute(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// nothing to decode into
+	if response.StatusCode == http.StatusNoContent || (res == nil && cfg.decoder == nil) {
+		return nil
+	}
+
+	var target any
+	if res != nil {
+		target = res
+	}
+
+	decoder := c.selectDecoder(response.Header.Get("Content-Type"), target, cfg)
+	if err = decoder.Decode(response.Body, target); err != nil {
+		return fmt.Errorf("Error Decoding Response: %w", err)
+	}
+
+	return nil
+}
+
+// newRequest builds a request with the encoded body, query params and merged headers
+func (c *Client) newRequest(ctx context.Context, method string, url string, body any, params map[string]string, headers map[string]string) (*http.Request, error) {
+	// if body exist, prep it for request
+	reqBody, contentType, err := encodeBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("Error Encoding Request Body: %w", err)
+	}
+
+	// init new http request to build on
+	req, err := http.NewRequestWithContext(ctx, method, c.resolveURL(url), reqBody)
+	if err != nil {
+		if closer, ok := reqBody.(io.Closer); ok {
+			closer.Close() // stop a streamed body's writer
+		}
+		return nil, fmt.Errorf("Error Building Request: %w", err)
+	}
+
+	// add query params
+	if len(params) > 0 {
+		query := req.URL.Query()
+		for key, value := range params {
+			query.Add(key, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+
+	// add default headers, then request headers on top
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	// ensure content type is in header
+	if req.Header.Get("Content-Type") == "" {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return req, nil
+}
+
+// execute sends the request and turns non-2xx responses into an *HTTPError.
+// On success the caller must close the response body.
+func (c *Client) execute(req *http.Request) (*http.Response, error) {
+	response, err := c.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error Making Request: %w", err)
+	}
+
+	// check status code
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
+		return nil, newHTTPError(response)
+	}
+
+	return response, nil
+}
+
+// streamingClient shares the client's transport but has no timeout, which would
+// cut long-lived connections such as WebSockets and event streams
+func (c *Client) streamingClient() *http.Client {
+	return &http.Client{Transport: c.httpClient.Transport}
+}
+
+// Get sends a GET request using the client and decodes the response into res.
+//
+// Example usage:
+//
+//	params := map[string]string{"page": "1", "limit": "10"}
+//	var users []User
+//	err := Get(client, "/users", &users, params, nil)
+func Get[T any](c *Client, url string, res *T, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return GetCtx(context.Background(), c, url, res, params, headers, opts...)
+}
+
+// GetCtx is like Get but honors cancellation and deadlines on ctx.
+func GetCtx[T any](ctx context.Context, c *Client, url string, res *T, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return DoCtx(ctx, c, http.MethodGet, url, res, nil, params, headers, opts...)
+}
+
+// Post sends a POST request using the client and decodes the response into res.
+// The body is encoded as in Do.
+//
+// Example usage:
+//
+//	newUser := User{Name: "John", Email: "john@example.com"}
+//	var createdUser User
+//	err := Post(client, "/users", &createdUser, newUser, nil, nil)
+func Post[T any](c *Client, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return PostCtx(context.Background(), c, url, res, body, params, headers, opts...)
+}
+
+// PostCtx is like Post but honors cancellation and deadlines on ctx.
+func PostCtx[T any](ctx context.Context, c *Client, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return DoCtx(ctx, c, http.MethodPost, url, res, body, params, headers, opts...)
+}
@@ -0,0 +1,328 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Body is a request body that encodes itself and reports its Content-Type.
+// Pass a Body as the body argument of Do, MakeRequest, etc. to send something
+// other than JSON. Any other non-nil value is JSON marshaled.
+type Body interface {
+	Encode() (io.Reader, string, error)
+}
+
+// BodyFunc adapts a function to the Body interface.
+type BodyFunc func() (io.Reader, string, error)
+
+func (f BodyFunc) Encode() (io.Reader, string, error) {
+	return f()
+}
+
+// JSONBody encodes v as application/json. This is what happens to plain values already.
+func JSONBody(v any) Body {
+	return BodyFunc(func() (io.Reader, string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(data), "application/json", nil
+	})
+}
+
+// XMLBody encodes v as application/xml, e.g. for SOAP endpoints.
+//
+// Example usage:
+//
+//	var out Envelope
+//	err := MakeRequest("POST", soapURL, &out, XMLBody(envelope), nil, map[string]string{"SOAPAction": "GetItem"})
+func XMLBody(v any) Body {
+	return BodyFunc(func() (io.Reader, string, error) {
+		data, err := xml.Marshal(v)
+		if err != nil {
+			return nil, "", err
+		}
+		return bytes.NewReader(append([]byte(xml.Header), data...)), "application/xml; charset=utf-8", nil
+	})
+}
+
+// FormBody encodes values as application/x-www-form-urlencoded.
+//
+// Example usage:
+//
+//	form := url.Values{"grant_type": {"password"}, "username": {"john"}}
+//	err := MakeRequest("POST", "https://api.example.com/login", &token, FormBody(form), nil, nil)
+func FormBody(values url.Values) Body {
+	return BodyFunc(func() (io.Reader, string, error) {
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+	})
+}
+
+// RawBody sends r as-is with the given Content-Type (application/octet-stream if empty).
+// Requests with a RawBody can only be retried when r is a *bytes.Buffer, *bytes.Reader or *strings.Reader.
+func RawBody(r io.Reader, contentType string) Body {
+	return BodyFunc(func() (io.Reader, string, error) {
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return r, contentType, nil
+	})
+}
+
+// FilePart is a file attached to a MultipartBody.
+type FilePart struct {
+	FieldName   string
+	FileName    string
+	ContentType string // defaults to application/octet-stream
+	Content     io.Reader
+}
+
+// FileFromPath builds a FilePart for the file at path, guessing the content type from its extension.
+// The file is opened when the body is encoded and closed once it has been sent.
+func FileFromPath(fieldName string, path string) FilePart {
+	return FilePart{
+		FieldName:   fieldName,
+		FileName:    filepath.Base(path),
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		Content:     &lazyFile{path: path},
+	}
+}
+
+// MultipartBody encodes fields and files as multipart/form-data. The body is streamed as it
+// is sent rather than buffered, so large files are not held in memory; as a consequence
+// requests with a MultipartBody are not retried. Files opened by FileFromPath are closed
+// once sent, while the Content of other FileParts is left for the caller to close.
+//
+// Example usage:
+//
+//	body := MultipartBody(
+//		map[string]string{"description": "March invoice"},
+//		FileFromPath("file", "./invoice.pdf"),
+//	)
+//	err := MakeRequest("POST", "https://api.example.com/uploads", &upload, body, nil, nil)
+func MultipartBody(fields map[string]string, files ...FilePart) Body {
+	return BodyFunc(func() (io.Reader, string, error) {
+		reader, pipe := io.Pipe()
+		writer := multipart.NewWriter(pipe)
+		go func() {
+			pipe.CloseWithError(writeMultipart(writer, fields, files))
+		}()
+		return reader, writer.FormDataContentType(), nil
+	})
+}
+
+// writeMultipart writes the parts of a MultipartBody, closing the files it opened itself
+func writeMultipart(writer *multipart.Writer, fields map[string]string, files []FilePart) error {
+	defer func() {
+		for _, file := range files {
+			if lazy, ok := file.Content.(*lazyFile); ok {
+				lazy.Close()
+			}
+		}
+	}()
+
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{
+			"name":     file.FieldName,
+			"filename": file.FileName,
+		}))
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.Content); err != nil {
+			return fmt.Errorf("Error Writing File Part (%s): %w", file.FileName, err)
+		}
+	}
+
+	return writer.Close()
+}
+
+// lazyFile opens its file on first read
+type lazyFile struct {
+	path string
+	file *os.File
+}
+
+func (f *lazyFile) Read(p []byte) (int, error) {
+	if f.file == nil {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Read(p)
+}
+
+func (f *lazyFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// encodeBody turns a request body argument into a reader and content type
+func encodeBody(body any) (io.Reader, string, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, "", nil
+	case Body:
+		return b.Encode()
+	default:
+		return JSONBody(b).Encode()
+	}
+}
+
+// Decoder decodes a response body into v.
+type Decoder interface {
+	Decode(r io.Reader, v any) error
+}
+
+// DecoderFunc adapts a function to the Decoder interface.
+type DecoderFunc func(r io.Reader, v any) error
+
+func (f DecoderFunc) Decode(r io.Reader, v any) error {
+	return f(r, v)
+}
+
+var (
+	// JSONDecoder unmarshals JSON into v.
+	JSONDecoder Decoder = DecoderFunc(func(r io.Reader, v any) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, v)
+	})
+
+	// XMLDecoder unmarshals XML into v.
+	XMLDecoder Decoder = DecoderFunc(func(r io.Reader, v any) error {
+		return xml.NewDecoder(r).Decode(v)
+	})
+
+	// BytesDecoder stores the raw body in v, which must be a *[]byte.
+	BytesDecoder Decoder = DecoderFunc(func(r io.Reader, v any) error {
+		out, ok := v.(*[]byte)
+		if !ok {
+			return fmt.Errorf("BytesDecoder needs a *[]byte, got %T", v)
+		}
+		data, err := io.ReadAll(r)
+		*out = data
+		return err
+	})
+
+	// StringDecoder stores the body as text in v, which must be a *string.
+	StringDecoder Decoder = DecoderFunc(func(r io.Reader, v any) error {
+		out, ok := v.(*string)
+		if !ok {
+			return fmt.Errorf("StringDecoder needs a *string, got %T", v)
+		}
+		data, err := io.ReadAll(r)
+		*out = string(data)
+		return err
+	})
+)
+
+// WriterDecoder streams the body to w without buffering it, ignoring v.
+//
+// Example usage:
+//
+//	file, _ := os.Create("export.zip")
+//	defer file.Close()
+//	err := Get[any](client, "/exports/42", nil, nil, nil, DecodeWith(WriterDecoder(file)))
+func WriterDecoder(w io.Writer) Decoder {
+	return DecoderFunc(func(r io.Reader, _ any) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// defaultDecoders maps response media types to decoders when none is chosen per request
+var defaultDecoders = map[string]Decoder{
+	"application/json": JSONDecoder,
+	"application/xml":  XMLDecoder,
+	"text/xml":         XMLDecoder,
+}
+
+// WithContentDecoder registers a decoder for responses with the given media type (e.g. "text/csv").
+func WithContentDecoder(mediaType string, decoder Decoder) Option {
+	return func(c *Client) {
+		if c.decoders == nil {
+			c.decoders = make(map[string]Decoder)
+		}
+		c.decoders[strings.ToLower(mediaType)] = decoder
+	}
+}
+
+// DecodeWith decodes this request's response with decoder, regardless of its Content-Type.
+//
+// Example usage:
+//
+//	var feed Feed
+//	err := Get(client, "/feed", &feed, nil, nil, DecodeWith(XMLDecoder))
+func DecodeWith(decoder Decoder) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.decoder = decoder
+	}
+}
+
+// selectDecoder picks a decoder for a response: an explicit per-request decoder first,
+// then by the type of v (*[]byte, or *string unless the response is JSON), then by the
+// response Content-Type, then JSON
+func (c *Client) selectDecoder(contentType string, v any, cfg *requestConfig) Decoder {
+	if cfg.decoder != nil {
+		return cfg.decoder
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch v.(type) {
+	case *[]byte:
+		return BytesDecoder
+	case *string:
+		// a JSON string response still decodes as JSON, anything else is kept as text
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return JSONDecoder
+		}
+		return StringDecoder
+	}
+
+	if decoder, ok := c.decoders[mediaType]; ok {
+		return decoder
+	}
+	if decoder, ok := defaultDecoders[mediaType]; ok {
+		return decoder
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONDecoder
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLDecoder
+	}
+	return JSONDecoder
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStringResultDecodesJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+	}{
+		{"application/json", `"hello"`, "hello"},
+		{"application/problem+json", `"hello"`, "hello"},
+		{"text/plain; charset=utf-8", `"hello"`, `"hello"`},
+		{"text/csv", "a,b\n1,2\n", "a,b\n1,2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			var got string
+			if err := Get(NewClient(), srv.URL, &got, nil, nil); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// trackingReader records whether it was closed
+type trackingReader struct {
+	io.Reader
+	closed bool
+}
+
+func (r *trackingReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestMultipartBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.txt")
+	if err := os.WriteFile(path, []byte("from disk"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	callerReader := &trackingReader{Reader: strings.NewReader("from memory")}
+
+	got := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Errorf("Content-Type: %v", err)
+			return
+		}
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			got[part.FormName()+"/"+part.FileName()] = string(data)
+		}
+	}))
+	defer srv.Close()
+
+	body := MultipartBody(
+		map[string]string{"description": "March"},
+		FileFromPath("file", path),
+		FilePart{FieldName: "note", FileName: "note.txt", Content: callerReader},
+	)
+	if err := Post[any](NewClient(), srv.URL, nil, body, nil, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	want := map[string]string{"description/": "March", "file/invoice.txt": "from disk", "note/note.txt": "from memory"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("part %s = %q, want %q", key, got[key], value)
+		}
+	}
+	if callerReader.closed {
+		t.Error("MultipartBody closed a reader it did not open")
+	}
+}
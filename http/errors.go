@@ -0,0 +1,167 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodySize caps how much of a failed response body is kept on an HTTPError
+const maxErrorBodySize = 1 << 20
+
+// HTTPError is returned when a request completes with a non-2xx status code.
+// It keeps the response status, headers and body so callers can inspect
+// the vendor's error explanation.
+//
+// Example usage:
+//
+//	err := MakeRequest("GET", "https://api.example.com/users/1", &user, nil, nil, nil)
+//	var httpErr *HTTPError
+//	if errors.As(err, &httpErr) {
+//		log.Printf("%s %s failed with %d: %s", httpErr.Method, httpErr.URL, httpErr.StatusCode, httpErr.Body)
+//	}
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	Method     string
+	URL        string
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("HTTP Error: %s %s: %s", e.Method, e.URL, e.Status)
+	if body := strings.TrimSpace(string(e.Body)); body != "" {
+		if len(body) > 512 {
+			body = body[:512] + "..."
+		}
+		msg += ": " + body
+	}
+	return msg
+}
+
+// newHTTPError builds an HTTPError from a non-2xx response, reading (part of) its body
+func newHTTPError(response *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+
+	httpErr := &HTTPError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Header:     response.Header,
+		Body:       body,
+	}
+	if response.Request != nil {
+		httpErr.Method = response.Request.Method
+		httpErr.URL = response.Request.URL.String()
+	}
+	return httpErr
+}
+
+// StatusCode returns the status code of an HTTPError wrapped in err, or 0 if there is none.
+func StatusCode(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is an HTTPError with status 404.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err is an HTTPError with status 401 or 403.
+func IsUnauthorized(err error) bool {
+	code := StatusCode(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// IsRateLimited reports whether err is an HTTPError with status 429.
+func IsRateLimited(err error) bool {
+	return StatusCode(err) == http.StatusTooManyRequests
+}
+
+// IsClientError reports whether err is an HTTPError with a 4xx status.
+func IsClientError(err error) bool {
+	code := StatusCode(err)
+	return code >= 400 && code < 500
+}
+
+// IsServerError reports whether err is an HTTPError with a 5xx status.
+func IsServerError(err error) bool {
+	return StatusCode(err) >= 500
+}
+
+func (e GraphQLError) Error() string {
+	if len(e.Path) > 0 {
+		return fmt.Sprintf("%s (path: %s)", e.Message, e.PathString())
+	}
+	return e.Message
+}
+
+// Code returns the error's extensions.code (e.g. "UNAUTHENTICATED"), or "" if it has none.
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// PathString returns the error's path joined with dots, e.g. "user.friends.0.name".
+func (e GraphQLError) PathString() string {
+	parts := make([]string, len(e.Path))
+	for i, segment := range e.Path {
+		switch v := segment.(type) {
+		case float64:
+			parts[i] = strconv.Itoa(int(v))
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// GraphQLErrors is returned when a GraphQL response contains an errors array.
+//
+// Example usage:
+//
+//	var gqlErrs GraphQLErrors
+//	if errors.As(err, &gqlErrs) {
+//		for _, e := range gqlErrs {
+//			log.Printf("%s at %v", e.Message, e.Path)
+//		}
+//	}
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, gqlErr := range e {
+		msgs[i] = gqlErr.Error()
+	}
+	return "GraphQL errors: " + strings.Join(msgs, "; ")
+}
+
+// HasCode reports whether any of the errors has the given extensions.code.
+func (e GraphQLErrors) HasCode(code string) bool {
+	for _, gqlErr := range e {
+		if gqlErr.Code() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// AtPath returns the errors whose path starts with the given segments, e.g. AtPath("user", "email").
+func (e GraphQLErrors) AtPath(segments ...string) GraphQLErrors {
+	prefix := strings.Join(segments, ".")
+	var matched GraphQLErrors
+	for _, gqlErr := range e {
+		path := gqlErr.PathString()
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			matched = append(matched, gqlErr)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// GraphQLClient sends GraphQL operations to a single endpoint. It supports
+// operation names, extensions, Automatic Persisted Queries and batching, and
+// returns partial data together with any GraphQL errors.
+type GraphQLClient struct {
+	client    *Client
+	url       string
+	headers   map[string]string
+	persisted atomic.Bool
+	hashes    sync.Map // query -> sha256 hex
+}
+
+// GraphQLOption configures a GraphQLClient.
+type GraphQLOption func(*GraphQLClient)
+
+// WithGraphQLHeaders sets headers sent with every operation.
+func WithGraphQLHeaders(headers map[string]string) GraphQLOption {
+	return func(gc *GraphQLClient) {
+		gc.headers = headers
+	}
+}
+
+// WithPersistedQueries enables Automatic Persisted Queries: operations are first sent as a
+// sha256 hash only, and resent with the full query if the server doesn't know the hash yet.
+// If the server reports that persisted queries are not supported, APQ is turned off. A hash-only
+// request rejected with 400, 404 or 405 is resent with the full query; other errors are returned.
+func WithPersistedQueries() GraphQLOption {
+	return func(gc *GraphQLClient) {
+		gc.persisted.Store(true)
+	}
+}
+
+// NewGraphQLClient creates a GraphQLClient for the endpoint at url, sending requests with c
+// (DefaultClient if nil).
+//
+// Example usage:
+//
+//	gql := NewGraphQLClient(client, "https://api.example.com/graphql", WithPersistedQueries())
+//
+//	var data struct {
+//		User User `json:"user"`
+//	}
+//	err := Execute(ctx, gql, GraphQLRequest{
+//		Query:         `query GetUser($id: ID!) { user(id: $id) { name email } }`,
+//		OperationName: "GetUser",
+//		Variables:     map[string]any{"id": "123"},
+//	}, &data)
+func NewGraphQLClient(c *Client, url string, opts ...GraphQLOption) *GraphQLClient {
+	if c == nil {
+		c = DefaultClient
+	}
+	gc := &GraphQLClient{client: c, url: url}
+	for _, opt := range opts {
+		opt(gc)
+	}
+	return gc
+}
+
+// persistedQuery is the APQ extension payload
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	Sha256Hash string `json:"sha256Hash"`
+}
+
+func (gc *GraphQLClient) hash(query string) string {
+	if hash, ok := gc.hashes.Load(query); ok {
+		return hash.(string)
+	}
+	sum := sha256.Sum256([]byte(query))
+	hash := hex.EncodeToString(sum[:])
+	gc.hashes.Store(query, hash)
+	return hash
+}
+
+// withPersistedQuery returns a copy of req carrying the APQ hash, with or without the query text
+func (gc *GraphQLClient) withPersistedQuery(req GraphQLRequest, includeQuery bool) GraphQLRequest {
+	extensions := make(map[string]any, len(req.Extensions)+1)
+	for key, value := range req.Extensions {
+		extensions[key] = value
+	}
+	extensions["persistedQuery"] = persistedQuery{Version: 1, Sha256Hash: gc.hash(req.Query)}
+	req.Extensions = extensions
+	if !includeQuery {
+		req.Query = ""
+	}
+	return req
+}
+
+// Execute sends a single operation and unmarshals its data into res. When the response
+// carries both data and errors, res is still filled with the partial data and the errors
+// are returned as GraphQLErrors.
+//
+// Parameters:
+//   - ctx: Context for the request
+//   - gc: The GraphQL client
+//   - req: The operation (Query, OperationName, Variables, Extensions)
+//   - res: Pointer to struct where response data will be unmarshaled
+//   - opts: Per-request options such as UseMiddleware
+//
+// Returns an error if the request fails (as *HTTPError for non-2xx), or GraphQLErrors if the response has errors.
+//
+// Example usage:
+//
+//	err := Execute(ctx, gql, GraphQLRequest{Query: query}, &data)
+//	var gqlErrs GraphQLErrors
+//	if errors.As(err, &gqlErrs) && data.User.Name != "" {
+//		// partial result, some fields failed
+//		for _, e := range gqlErrs {
+//			log.Printf("%s at %s (%s)", e.Message, e.PathString(), e.Code())
+//		}
+//	}
+func Execute[T any](ctx context.Context, gc *GraphQLClient, req GraphQLRequest, res *T, opts ...RequestOption) error {
+	gqlRes, err := ExecuteResponse[T](ctx, gc, req, opts...)
+	if err != nil {
+		return err
+	}
+
+	*res = gqlRes.Data
+	if len(gqlRes.Errors) > 0 {
+		return GraphQLErrors(gqlRes.Errors)
+	}
+	return nil
+}
+
+// ExecuteResponse is like Execute but returns the whole response, including extensions,
+// without turning GraphQL errors into a Go error.
+func ExecuteResponse[T any](ctx context.Context, gc *GraphQLClient, req GraphQLRequest, opts ...RequestOption) (GraphQLResponse[T], error) {
+	var gqlRes GraphQLResponse[T]
+
+	if gc.persisted.Load() && req.Query != "" {
+		err := DoCtx(ctx, gc.client, http.MethodPost, gc.url, &gqlRes, gc.withPersistedQuery(req, false), nil, gc.headers, opts...)
+		errs := GraphQLErrors(gqlRes.Errors)
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			// the APQ errors may come with a 4xx status
+			var body struct {
+				Errors GraphQLErrors `json:"errors"`
+			}
+			json.Unmarshal(httpErr.Body, &body)
+			errs = body.Errors
+		} else if err != nil {
+			return gqlRes, fmt.Errorf("GraphQL request failed: %w", err)
+		}
+
+		switch {
+		case apqError(errs, "PERSISTED_QUERY_NOT_SUPPORTED", "PersistedQueryNotSupported"):
+			gc.persisted.Store(false)
+		case apqError(errs, "PERSISTED_QUERY_NOT_FOUND", "PersistedQueryNotFound"):
+			// register the query alongside its hash
+			req = gc.withPersistedQuery(req, true)
+		case err == nil:
+			return gqlRes, nil
+		case apqRejected(httpErr.StatusCode):
+			// some servers answer unknown hashes with a bare 4xx, fall back to the full query
+		default:
+			return gqlRes, fmt.Errorf("GraphQL request failed: %w", err)
+		}
+		gqlRes = GraphQLResponse[T]{}
+	}
+
+	if err := DoCtx(ctx, gc.client, http.MethodPost, gc.url, &gqlRes, req, nil, gc.headers, opts...); err != nil {
+		return gqlRes, fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	return gqlRes, nil
+}
+
+// apqRejected reports whether a status is one servers without APQ answer a hash-only request with.
+// Anything else, such as 401 or 500, is a real failure and not retried with the full query.
+func apqRejected(status int) bool {
+	return status == http.StatusBadRequest || status == http.StatusNotFound || status == http.StatusMethodNotAllowed
+}
+
+// apqError matches APQ errors by code or, for older servers, by message
+func apqError(errs GraphQLErrors, code string, message string) bool {
+	for _, gqlErr := range errs {
+		if gqlErr.Code() == code || gqlErr.Message == message {
+			return true
+		}
+	}
+	return false
+}
+
+// Batch sends several operations in one HTTP request (as a JSON array) and returns
+// one response per operation, in order. Each response's Data is left as raw JSON;
+// decode it with json.Unmarshal or DecodeData.
+//
+// Example usage:
+//
+//	results, err := Batch(ctx, gql, []GraphQLRequest{
+//		{Query: `query { me { id } }`},
+//		{Query: `query { shop { name } }`},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	var me Me
+//	err = DecodeData(results[0], &me)
+func Batch(ctx context.Context, gc *GraphQLClient, reqs []GraphQLRequest, opts ...RequestOption) ([]GraphQLResponse[json.RawMessage], error) {
+	var results []GraphQLResponse[json.RawMessage]
+	if err := DoCtx(ctx, gc.client, http.MethodPost, gc.url, &results, reqs, nil, gc.headers, opts...); err != nil {
+		return nil, fmt.Errorf("GraphQL batch request failed: %w", err)
+	}
+	if len(results) != len(reqs) {
+		return results, fmt.Errorf("GraphQL batch returned %d results for %d operations", len(results), len(reqs))
+	}
+	return results, nil
+}
+
+// DecodeData unmarshals the data of a batched response into res, returning its errors as GraphQLErrors.
+func DecodeData[T any](gqlRes GraphQLResponse[json.RawMessage], res *T) error {
+	if len(gqlRes.Data) > 0 && string(gqlRes.Data) != "null" {
+		if err := json.Unmarshal(gqlRes.Data, res); err != nil {
+			return fmt.Errorf("Error Unmarshaling GraphQL Data: %w", err)
+		}
+	}
+	if len(gqlRes.Errors) > 0 {
+		return GraphQLErrors(gqlRes.Errors)
+	}
+	return nil
+}
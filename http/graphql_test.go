@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// apqServer answers hash-only requests with status and body, and full queries with data
+func apqServer(t *testing.T, status int, body string) (*httptest.Server, *[]GraphQLRequest) {
+	t.Helper()
+	var received []GraphQLRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Decode: %v", err)
+		}
+		received = append(received, req)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Query == "" {
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"ok": true}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &received
+}
+
+func TestPersistedQueryFallback(t *testing.T) {
+	notFound := `{"errors": [{"message": "PersistedQueryNotFound", "extensions": {"code": "PERSISTED_QUERY_NOT_FOUND"}}]}`
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantRequests int
+		wantHash     bool // the full query is sent with its hash to register it
+	}{
+		{name: "not found", status: http.StatusOK, body: notFound, wantRequests: 2, wantHash: true},
+		{name: "not found with 400", status: http.StatusBadRequest, body: notFound, wantRequests: 2, wantHash: true},
+		{name: "bare 400", status: http.StatusBadRequest, body: `bad request`, wantRequests: 2},
+		{name: "bare 405", status: http.StatusMethodNotAllowed, wantRequests: 2},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: true, wantRequests: 1},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, received := apqServer(t, tt.status, tt.body)
+			gql := NewGraphQLClient(NewClient(), srv.URL, WithPersistedQueries())
+
+			var data struct{ OK bool }
+			err := Execute(context.Background(), gql, GraphQLRequest{Query: `{ ok }`}, &data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(*received) != tt.wantRequests {
+				t.Fatalf("sent %d requests, want %d", len(*received), tt.wantRequests)
+			}
+			if tt.wantErr {
+				return
+			}
+			last := (*received)[len(*received)-1]
+			if !data.OK || last.Query == "" || (last.Extensions["persistedQuery"] != nil) != tt.wantHash {
+				t.Fatalf("data %+v, last request %+v", data, last)
+			}
+		})
+	}
+}
+
+func TestPersistedQueryNotSupportedTurnsAPQOff(t *testing.T) {
+	srv, received := apqServer(t, http.StatusOK, `{"errors": [{"message": "PersistedQueryNotSupported"}]}`)
+	gql := NewGraphQLClient(NewClient(), srv.URL, WithPersistedQueries())
+
+	for range 2 {
+		var data struct{ OK bool }
+		if err := Execute(context.Background(), gql, GraphQLRequest{Query: `{ ok }`}, &data); err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+	}
+	// hash-only, full query, then full queries only
+	if len(*received) != 3 || (*received)[2].Extensions != nil {
+		t.Fatalf("requests %+v", *received)
+	}
+}
@@ -1,124 +1,67 @@
 package http
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"time"
+	"context"
 )
 
 // MakeRequest sends an HTTP request with the specified method, URL, and parameters,
-// then unmarshals the JSON response into the provided struct.
+// then unmarshals the JSON response into the provided struct. It is a thin wrapper
+// around Do using DefaultClient, so connections are reused across calls.
 //
 // Parameters:
 //   - method: HTTP method (GET, POST, PUT, PATCH, DELETE, etc.)
 //   - url: The target URL
-//   - res: Pointer to struct where response will be unmarshaled
-//   - body: Request body (will be JSON marshaled), pass nil for GET requests
+//   - res: Pointer to the value the response is decoded into (JSON, XML, *string or *[]byte)
+//   - body: Request body, pass nil for GET requests. A Body (FormBody, MultipartBody, XMLBody, RawBody)
+//     is encoded as such, any other value is JSON marshaled
 //   - params: Query parameters as key-value pairs
 //   - headers: HTTP headers as key-value pairs
-//   - printRawBody: true/false to print the raw unmarshaled body
+//   - opts: Per-request options such as DecodeWith or UseMiddleware(Dumper(...))
 //
-// Returns an error if the request fails, status code is not 2xx, or JSON unmarshaling fails.
+// Returns an error if the request fails, status code is not 2xx (as *HTTPError), or JSON unmarshaling fails.
 //
 // Example usage:
 //
 //	// GET request
 //	var user User
-//	err := MakeRequest("GET", "https://api.example.com/users/1", &user, nil, nil, nil, false)
+//	err := MakeRequest("GET", "https://api.example.com/users/1", &user, nil, nil, nil)
 //
 //	// POST request with body
 //	newUser := User{Name: "John", Email: "john@example.com"}
 //	var createdUser User
-//	err := MakeRequest("POST", "https://api.example.com/users", &createdUser, newUser, nil, nil, false)
+//	err := MakeRequest("POST", "https://api.example.com/users", &createdUser, newUser, nil, nil)
 //
 //	// GET with query parameters
 //	params := map[string]string{"page": "1", "limit": "10"}
 //	var users []User
-//	err := MakeRequest("GET", "https://api.example.com/users", &users, nil, params, nil, false)
+//	err := MakeRequest("GET", "https://api.example.com/users", &users, nil, params, nil)
 //
 //	// POST with custom headers
 //	headers := map[string]string{"Authorization": "Bearer token123"}
-//	err := MakeRequest("POST", "https://api.example.com/protected", &result, data, nil, headers, false)
-func MakeRequest[T any](method string, url string, res *T, body any, params map[string]string, headers map[string]string, printRawBody ...bool) error {
-	// create client
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	// if body exist, prep it for request
-	var reqBody io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("Error Marshaling Request Body: %w", err)
-		}
-		reqBody = bytes.NewBuffer(jsonBody)
-	}
-
-	// init new http request to build on
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return fmt.Errorf("Error Building Request: %w", err)
-	}
-
-	// add query params
-	query := req.URL.Query()
-	for key, value := range params {
-		query.Add(key, value)
-	}
-	req.URL.RawQuery = query.Encode()
-
-	// ensure content type is in header
-	if headers == nil {
-		headers = make(map[string]string)
-	}
-	if _, exists := headers["Content-Type"]; !exists {
-		headers["Content-Type"] = "application/json"
-	}
-
-	// add headers
-	for key, value := range headers {
-		req.Header.Add(key, value)
-	}
-
-	// make request
-	response, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("Error Making Request: %w", err)
-	}
-	defer response.Body.Close()
-
-	// check status code
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return fmt.Errorf("HTTP Error: %d %s", response.StatusCode, response.Status)
-	}
-
-	// read the request body
-	responseBody, err := io.ReadAll(response.Body)
-	if err != nil {
-		return fmt.Errorf("Error Reading Response Body: %w", err)
-	}
-
-	shouldPrint := false
-	if len(printRawBody) > 0 {
-		shouldPrint = printRawBody[0]
-	}
-	if shouldPrint {
-		fmt.Printf("Response Body: %v", string(responseBody))
-	}
-
-	if err = json.Unmarshal(responseBody, res); err != nil {
-		return fmt.Errorf("Error Unmarshaling Response: %w", err)
-	}
+//	err := MakeRequest("POST", "https://api.example.com/protected", &result, data, nil, headers)
+//
+//	// Dump the raw request and response with secrets masked
+//	err := MakeRequest("GET", "https://api.example.com/users/1", &user, nil, nil, nil, UseMiddleware(Dumper(DumpOptions{})))
+func MakeRequest[T any](method string, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return DoCtx(context.Background(), DefaultClient, method, url, res, body, params, headers, opts...)
+}
 
-	return nil
+// MakeRequestCtx is like MakeRequest but honors cancellation and deadlines on ctx.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//
+//	var user User
+//	err := MakeRequestCtx(ctx, "GET", "https://api.example.com/users/1", &user, nil, nil, nil)
+func MakeRequestCtx[T any](ctx context.Context, method string, url string, res *T, body any, params map[string]string, headers map[string]string, opts ...RequestOption) error {
+	return DoCtx(ctx, DefaultClient, method, url, res, body, params, headers, opts...)
 }
 
 // MakeGraphQLRequest sends a GraphQL request to the specified endpoint and
-// unmarshals the response data into the provided struct.
+// unmarshals the response data into the provided struct. It is a thin wrapper
+// around GraphQL using DefaultClient.
 //
 // Parameters:
 //   - url: GraphQL endpoint URL
@@ -126,8 +69,9 @@ func MakeRequest[T any](method string, url string, res *T, body any, params map[
 //   - variables: Variables for the GraphQL query (can be nil)
 //   - res: Pointer to struct where response data will be unmarshaled
 //   - headers: HTTP headers as key-value pairs (Authorization, etc.)
+//   - opts: Per-request options such as UseMiddleware(Dumper(...))
 //
-// Returns an error if the request fails, contains GraphQL errors, or JSON unmarshaling fails.
+// Returns an error if the request fails, contains GraphQL errors (as GraphQLErrors), or JSON unmarshaling fails.
 //
 // Example usage:
 //
@@ -151,22 +95,49 @@ func MakeRequest[T any](method string, url string, res *T, body any, params map[
 //	variables := map[string]interface{}{"input": map[string]interface{}{"name": "John", "email": "john@example.com"}}
 //	var result CreateUserResult
 //	err := MakeGraphQLRequest("https://api.example.com/graphql", mutation, variables, &result, nil)
-func MakeGraphQLRequest[T any](url string, query string, variables map[string]any, res *T, headers map[string]string, printRawBody ...bool) error {
-	gqlReq := GraphQLRequest{
-		Query:     query,
-		Variables: variables,
-	}
+func MakeGraphQLRequest[T any](url string, query string, variables map[string]any, res *T, headers map[string]string, opts ...RequestOption) error {
+	return GraphQLCtx(context.Background(), DefaultClient, url, query, variables, res, headers, opts...)
+}
 
-	var gqlRes GraphQLResponse[T]
-	err := MakeRequest("POST", url, &gqlRes, gqlReq, nil, headers, printRawBody...)
-	if err != nil {
-		return fmt.Errorf("GraphQL request failed: %w", err)
-	}
+// MakeGraphQLRequestCtx is like MakeGraphQLRequest but honors cancellation and deadlines on ctx.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//
+//	var user User
+//	err := MakeGraphQLRequestCtx(ctx, "https://api.example.com/graphql", query, nil, &user, nil)
+func MakeGraphQLRequestCtx[T any](ctx context.Context, url string, query string, variables map[string]any, res *T, headers map[string]string, opts ...RequestOption) error {
+	return GraphQLCtx(ctx, DefaultClient, url, query, variables, res, headers, opts...)
+}
 
-	if len(gqlRes.Errors) > 0 {
-		return fmt.Errorf("GraphQL errors: %v", gqlRes.Errors)
-	}
+// GraphQL sends a GraphQL request using the client and unmarshals the response data into res.
+//
+// Parameters:
+//   - c: The client to send the request with
+//   - url: GraphQL endpoint URL, absolute or relative to the client's base URL
+//   - query: GraphQL query or mutation string
+//   - variables: Variables for the GraphQL query (can be nil)
+//   - res: Pointer to struct where response data will be unmarshaled
+//   - headers: HTTP headers as key-value pairs, merged over the client's default headers
+//
+// Returns an error if the request fails, contains GraphQL errors (as GraphQLErrors), or JSON unmarshaling fails.
+//
+// Example usage:
+//
+//	client := NewClient(WithBaseURL("https://api.example.com"), WithHeaders(map[string]string{"Authorization": "Bearer token123"}))
+//
+//	query := `query GetUser($id: ID!) { user(id: $id) { name email } }`
+//	var user User
+//	err := GraphQL(client, "/graphql", query, map[string]any{"id": "123"}, &user, nil)
+func GraphQL[T any](c *Client, url string, query string, variables map[string]any, res *T, headers map[string]string, opts ...RequestOption) error {
+	return GraphQLCtx(context.Background(), c, url, query, variables, res, headers, opts...)
+}
 
-	*res = gqlRes.Data
-	return nil
+// GraphQLCtx is like GraphQL but honors cancellation and deadlines on ctx.
+// If the response has both data and errors, res is filled with the partial data.
+func GraphQLCtx[T any](ctx context.Context, c *Client, url string, query string, variables map[string]any, res *T, headers map[string]string, opts ...RequestOption) error {
+	gc := NewGraphQLClient(c, url, WithGraphQLHeaders(headers))
+	return Execute(ctx, gc, GraphQLRequest{Query: query, Variables: variables}, res, opts...)
 }
@@ -0,0 +1,452 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a RoundTripper to add behaviour around every request,
+// such as auth, logging or metrics. It runs once per attempt, so retried
+// requests pass through it again.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware adds middleware to the client. The first middleware given is the outermost,
+// so WithMiddleware(a, b) runs a, then b, then the transport.
+//
+// Example usage:
+//
+//	client := NewClient(
+//		WithBaseURL("https://api.example.com"),
+//		WithMiddleware(
+//			Logger(slog.Default()),
+//			BearerAuth(os.Getenv("API_TOKEN")),
+//		),
+//	)
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// UseMiddleware adds middleware for a single request. It runs inside the client's middleware.
+//
+// Example usage:
+//
+//	// dump this one call to stderr
+//	err := MakeRequest("GET", url, &res, nil, nil, nil, UseMiddleware(Dumper(DumpOptions{Writer: os.Stderr})))
+func UseMiddleware(mw ...Middleware) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.middleware = append(cfg.middleware, mw...)
+	}
+}
+
+// chain wraps transport with middleware, the first one ending up outermost
+func chain(transport http.RoundTripper, mw []Middleware) http.RoundTripper {
+	for i := len(mw) - 1; i >= 0; i-- {
+		transport = mw[i](transport)
+	}
+	return transport
+}
+
+type requestMiddlewareKey struct{}
+
+// withRequestMiddleware stores per-request middleware on the request context
+func withRequestMiddleware(ctx context.Context, mw []Middleware) context.Context {
+	if len(mw) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, requestMiddlewareKey{}, mw)
+}
+
+// perRequestTransport applies middleware stored on the request context by UseMiddleware
+type perRequestTransport struct {
+	next http.RoundTripper
+}
+
+func (t perRequestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mw, _ := req.Context().Value(requestMiddlewareKey{}).([]Middleware)
+	if len(mw) == 0 {
+		return t.next.RoundTrip(req)
+	}
+	return chain(t.next, mw).RoundTrip(req)
+}
+
+// setHeader returns a copy of req with the header set, leaving the caller's request untouched
+func setHeader(req *http.Request, key string, value string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set(key, value)
+	return req
+}
+
+// redirectedAway reports whether req is a redirect to a different host than the request
+// that started it. Auth middleware runs on every redirect hop, so it checks this to keep
+// credentials from following a redirect to another host.
+func redirectedAway(req *http.Request) bool {
+	original := req
+	for original.Response != nil && original.Response.Request != nil {
+		original = original.Response.Request
+	}
+	return !strings.EqualFold(req.URL.Host, original.URL.Host)
+}
+
+// BearerAuth sets "Authorization: Bearer <token>" on every request, but not on redirects to another host.
+func BearerAuth(token string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if redirectedAway(req) {
+				return next.RoundTrip(req)
+			}
+			return next.RoundTrip(setHeader(req, "Authorization", "Bearer "+token))
+		})
+	}
+}
+
+// BasicAuth sets HTTP basic auth credentials on every request, but not on redirects to another host.
+func BasicAuth(username string, password string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if redirectedAway(req) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(username, password)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// APIKey sets an API key header (e.g. "X-Api-Key") on every request, but not on redirects to another host.
+func APIKey(header string, key string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if redirectedAway(req) {
+				return next.RoundTrip(req)
+			}
+			return next.RoundTrip(setHeader(req, header, key))
+		})
+	}
+}
+
+// RequestID sets a random request ID header (e.g. "X-Request-ID") unless the request already has one.
+func RequestID(header string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) != "" {
+				return next.RoundTrip(req)
+			}
+			id := make([]byte, 16)
+			rand.Read(id)
+			return next.RoundTrip(setHeader(req, header, hex.EncodeToString(id)))
+		})
+	}
+}
+
+// Logger logs every request with its method, URL, status and duration using log/slog.
+// Failed requests are logged at error level. Credentials in the URL are masked: userinfo,
+// the query parameters in defaultRedactParams (api_key, access_token, sig, ...) and any
+// further redactParams.
+func Logger(logger *slog.Logger, redactParams ...string) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	redactParams = append(slices.Clip(defaultRedactParams), redactParams...)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			attrs := []any{
+				slog.String("method", req.Method),
+				slog.String("url", redactURL(req.URL, redactParams)),
+				slog.Duration("duration", time.Since(start)),
+			}
+			switch {
+			case err != nil:
+				logger.ErrorContext(req.Context(), "http request failed", append(attrs, slog.Any("error", err))...)
+			case resp.StatusCode >= 400:
+				logger.WarnContext(req.Context(), "http request", append(attrs, slog.Int("status", resp.StatusCode))...)
+			default:
+				logger.InfoContext(req.Context(), "http request", append(attrs, slog.Int("status", resp.StatusCode))...)
+			}
+			return resp, err
+		})
+	}
+}
+
+// defaultRedactParams are query parameters that commonly carry credentials, masked by
+// Logger and by Dumper unless RedactParams is set
+var defaultRedactParams = []string{
+	"api_key", "apikey", "key", "access_token", "token", "client_secret", "password", "sig", "signature",
+}
+
+// redactURL masks userinfo and the given query parameters of a URL before logging it
+func redactURL(u *url.URL, params []string) string {
+	clean := *u
+	if clean.User != nil {
+		clean.User = url.User("REDACTED")
+	}
+	clean.RawQuery = redactQuery(clean.RawQuery, params)
+	return clean.String()
+}
+
+// redactQuery masks the values of the given query parameters. A query that can't be
+// parsed is masked as a whole.
+func redactQuery(rawQuery string, params []string) string {
+	if rawQuery == "" || len(params) == 0 {
+		return rawQuery
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redacted
+	}
+	masked := false
+	for key := range query {
+		if containsFold(params, key) {
+			query[key] = []string{redacted}
+			masked = true
+		}
+	}
+	if !masked {
+		return rawQuery
+	}
+	return strings.ReplaceAll(query.Encode(), url.QueryEscape(redacted), redacted)
+}
+
+// DumpOptions configures the Dumper middleware.
+type DumpOptions struct {
+	Writer        io.Writer // defaults to os.Stdout
+	RedactHeaders []string  // headers to mask, defaults to Authorization, Cookie, Set-Cookie, X-Api-Key, Proxy-Authorization
+	RedactParams  []string  // query parameters to mask in the URL, defaults to api_key, access_token, sig and others
+	RedactFields  []string  // JSON or form fields to mask anywhere in the body, e.g. "password", "access_token"
+	MaxBody       int       // bytes of each body to print, defaults to 64KB; longer bodies are redacted before they are cut
+	SkipRequest   bool      // only dump responses
+}
+
+// defaultRedactHeaders are masked by Dumper unless RedactHeaders is set
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Proxy-Authorization"}
+
+const redacted = "[REDACTED]"
+
+// Dumper writes every request and response, headers and body, to a writer with
+// sensitive headers and fields masked. The response body is dumped as it is read,
+// so streaming responses keep working.
+//
+// With RedactFields set, a JSON or form body that cannot be parsed, such as a response
+// longer than MaxBody, is masked as a whole rather than printed with its fields intact.
+// Bodies of other content types are printed as-is.
+//
+// Example usage:
+//
+//	client := NewClient(WithMiddleware(Dumper(DumpOptions{
+//		Writer:       os.Stderr,
+//		RedactFields: []string{"password", "ssn"},
+//	})))
+func Dumper(opts DumpOptions) Middleware {
+	if opts.Writer == nil {
+		opts.Writer = os.Stdout
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = defaultRedactHeaders
+	}
+	if opts.RedactParams == nil {
+		opts.RedactParams = defaultRedactParams
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = 64 << 10
+	}
+	var mu sync.Mutex
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !opts.SkipRequest {
+				// the whole body is read so it can be redacted before it is cut to MaxBody
+				var body []byte
+				if req.GetBody != nil {
+					if reader, err := req.GetBody(); err == nil {
+						body, _ = io.ReadAll(reader)
+						reader.Close()
+					}
+				}
+				var buf bytes.Buffer
+				fmt.Fprintf(&buf, "> %s %s\n", req.Method, redactURL(req.URL, opts.RedactParams))
+				opts.writeHeaders(&buf, "> ", req.Header)
+				opts.writeBody(&buf, req.Header.Get("Content-Type"), body, len(body))
+
+				mu.Lock()
+				opts.Writer.Write(buf.Bytes())
+				mu.Unlock()
+			}
+
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				mu.Lock()
+				fmt.Fprintf(opts.Writer, "< error: %v\n\n", err)
+				mu.Unlock()
+				return resp, err
+			}
+
+			resp.Body = &dumpBody{ReadCloser: resp.Body, resp: resp, opts: &opts, mu: &mu}
+			return resp, nil
+		})
+	}
+}
+
+func (o *DumpOptions) writeHeaders(w io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if containsFold(o.RedactHeaders, key) {
+				value = redacted
+			}
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, key, value)
+		}
+	}
+}
+
+// writeBody writes body, the first bytes of a body size bytes long, redacted and cut to MaxBody
+func (o *DumpOptions) writeBody(w io.Writer, contentType string, body []byte, size int) {
+	if size > 0 {
+		fmt.Fprintf(w, "\n%s\n", o.formatBody(contentType, body, size))
+	}
+	fmt.Fprintln(w)
+}
+
+func (o *DumpOptions) formatBody(contentType string, body []byte, size int) []byte {
+	body, ok := redactBody(contentType, body, o.RedactFields)
+	if !ok {
+		return fmt.Appendf(nil, "%s (%d byte body that could not be parsed for redaction)", redacted, size)
+	}
+	if len(body) > o.MaxBody || size > o.MaxBody {
+		cut := slices.Clip(body[:min(len(body), o.MaxBody)])
+		return fmt.Appendf(cut, "\n... (%d byte body cut to %d)", size, o.MaxBody)
+	}
+	return body
+}
+
+// dumpBody captures the first MaxBody bytes of a response body as it is read and dumps
+// the response on Close
+type dumpBody struct {
+	io.ReadCloser
+	resp *http.Response
+	opts *DumpOptions
+	mu   *sync.Mutex
+	buf  bytes.Buffer
+	size int
+	once sync.Once
+}
+
+func (b *dumpBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	if room := b.opts.MaxBody - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+// Write passes through to upgraded (101 Switching Protocols) connections
+func (b *dumpBody) Write(p []byte) (int, error) {
+	w, ok := b.ReadCloser.(io.Writer)
+	if !ok {
+		return 0, fmt.Errorf("response body is not writable")
+	}
+	return w.Write(p)
+}
+
+func (b *dumpBody) Close() error {
+	b.once.Do(func() {
+		var out bytes.Buffer
+		fmt.Fprintf(&out, "< %s %s\n", b.resp.Proto, b.resp.Status)
+		b.opts.writeHeaders(&out, "< ", b.resp.Header)
+		b.opts.writeBody(&out, b.resp.Header.Get("Content-Type"), b.buf.Bytes(), b.size)
+
+		b.mu.Lock()
+		b.opts.Writer.Write(out.Bytes())
+		b.mu.Unlock()
+	})
+	return b.ReadCloser.Close()
+}
+
+// redactBody masks the given fields in JSON and form bodies, other bodies are returned as-is.
+// It returns false for a JSON or form body that could not be parsed, and so was not redacted.
+func redactBody(contentType string, body []byte, fields []string) ([]byte, bool) {
+	if len(fields) == 0 || len(body) == 0 {
+		return body, true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return body, false
+		}
+		for key := range values {
+			if containsFold(fields, key) {
+				values[key] = []string{redacted}
+			}
+		}
+		return []byte(values.Encode()), true
+
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var doc any
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return body, false
+		}
+		out, err := json.Marshal(redactJSON(doc, fields))
+		if err != nil {
+			return body, false
+		}
+		return out, true
+	}
+	return body, true
+}
+
+// redactJSON walks a decoded JSON document masking matching keys
+func redactJSON(doc any, fields []string) any {
+	switch v := doc.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if containsFold(fields, key) {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(value, fields)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i], fields)
+		}
+	}
+	return doc
+}
+
+func containsFold(values []string, want string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, want) })
+}
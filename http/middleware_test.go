@@ -0,0 +1,123 @@
+package http
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDumperRedactsBeforeTruncating(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"padding": "` + strings.Repeat("x", 100) + `", "token": "response-secret"}`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	client := NewClient(WithMiddleware(Dumper(DumpOptions{Writer: &out, RedactFields: []string{"password", "token"}, MaxBody: 40})))
+	body := map[string]string{"padding": strings.Repeat("y", 100), "password": "request-secret"}
+	if err := Post[any](client, srv.URL, nil, body, nil, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+
+	dump := out.String()
+	for _, secret := range []string{"request-secret", "response-secret"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("dump leaks %q:\n%s", secret, dump)
+		}
+	}
+	if !strings.Contains(dump, "cut to 40") {
+		t.Errorf("request body was not cut to MaxBody:\n%s", dump)
+	}
+}
+
+func TestAuthIsNotSentOnCrossHostRedirects(t *testing.T) {
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range []string{"Authorization", "X-Api-Key"} {
+			if r.Header.Get(header) != "" {
+				leaked = append(leaked, header)
+			}
+		}
+	}))
+	defer other.Close()
+
+	var sameHost []string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			http.Redirect(w, r, "/moved", http.StatusFound)
+		case "/moved":
+			sameHost = append(sameHost, r.Header.Get("Authorization"))
+			http.Redirect(w, r, other.URL+"/elsewhere", http.StatusFound)
+		}
+	}))
+	defer origin.Close()
+
+	middleware := map[string]Middleware{
+		"bearer":  BearerAuth("secret"),
+		"basic":   BasicAuth("user", "secret"),
+		"api key": APIKey("X-Api-Key", "secret"),
+	}
+	for name, mw := range middleware {
+		t.Run(name, func(t *testing.T) {
+			leaked, sameHost = nil, nil
+			if err := Get[any](NewClient(WithMiddleware(mw)), origin.URL+"/start", nil, nil, nil); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if len(leaked) > 0 {
+				t.Errorf("%v sent to another host", leaked)
+			}
+			if name != "api key" && (len(sameHost) != 1 || sameHost[0] == "") {
+				t.Errorf("credentials dropped on a same-host redirect: %q", sameHost)
+			}
+		})
+	}
+}
+
+func TestLoggerRedactsQueryCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var logs, dump bytes.Buffer
+	client := NewClient(WithMiddleware(
+		Logger(slog.New(slog.NewTextHandler(&logs, nil)), "session"),
+		Dumper(DumpOptions{Writer: &dump}),
+	))
+	params := map[string]string{"api_key": "key-secret", "access_token": "token-secret", "session": "session-secret", "page": "2"}
+	if err := Get[any](client, srv.URL+"/items", nil, params, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	for name, out := range map[string]string{"log": logs.String(), "dump": dump.String()} {
+		for _, secret := range []string{"key-secret", "token-secret"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s contains %q:\n%s", name, secret, out)
+			}
+		}
+		if !strings.Contains(out, "page=2") || !strings.Contains(out, "api_key="+redacted) {
+			t.Errorf("%s should keep other parameters and mask api_key:\n%s", name, out)
+		}
+	}
+	if strings.Contains(logs.String(), "session-secret") {
+		t.Errorf("log contains the extra redacted parameter:\n%s", logs.String())
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"page=2&sort=name":      "page=2&sort=name",
+		"sig=abc&page=2":        "page=2&sig=" + redacted,
+		"API_KEY=abc":           "API_KEY=" + redacted,
+		"page=%zz&access_token": redacted,
+	}
+	for query, want := range tests {
+		if got := redactQuery(query, defaultRedactParams); got != want {
+			t.Errorf("redactQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is an OAuth2 access token as returned by a token endpoint.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresIn    int       `json:"expires_in,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	Expiry       time.Time `json:"-"`
+}
+
+// Valid reports whether the token is set and does not expire within leeway.
+func (t *Token) Valid(leeway time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(leeway).Before(t.Expiry)
+}
+
+// TokenSource supplies OAuth2 tokens. Implementations must be safe for concurrent use.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// OAuth2Config describes an OAuth2 token endpoint and the client's credentials.
+type OAuth2Config struct {
+	TokenURL       string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+	EndpointParams url.Values    // extra form values sent to the token endpoint, e.g. "audience"
+	AuthInBody     bool          // send client_id/client_secret as form values instead of basic auth
+	ExpiryLeeway   time.Duration // refresh this long before expiry, defaults to 30s
+	Client         *Client       // client used to call the token endpoint, defaults to a plain client without middleware
+}
+
+// tokenClient calls token endpoints when OAuth2Config.Client is not set. It is deliberately not
+// DefaultClient, which may itself authenticate with the token source and so call back into it.
+var tokenClient = NewClient()
+
+// ClientCredentials returns a TokenSource using the client_credentials grant.
+// Tokens are cached and fetched again shortly before they expire.
+//
+// Example usage:
+//
+//	tokens := ClientCredentials(OAuth2Config{
+//		TokenURL:     "https://auth.example.com/oauth/token",
+//		ClientID:     os.Getenv("CLIENT_ID"),
+//		ClientSecret: os.Getenv("CLIENT_SECRET"),
+//		Scopes:       []string{"orders:read"},
+//	})
+//	client := NewClient(WithBaseURL("https://api.example.com"), WithTokenSource(tokens))
+func ClientCredentials(cfg OAuth2Config) TokenSource {
+	return &cachedTokenSource{
+		leeway: cfg.leeway(),
+		fetch: func(ctx context.Context, _ *Token) (*Token, error) {
+			form := url.Values{"grant_type": {"client_credentials"}}
+			return cfg.requestToken(ctx, form)
+		},
+	}
+}
+
+// RefreshToken returns a TokenSource using the refresh_token grant, starting from refreshToken.
+// If the endpoint rotates refresh tokens, the newest one is used for the next refresh.
+//
+// Example usage:
+//
+//	tokens := RefreshToken(OAuth2Config{
+//		TokenURL:     "https://auth.example.com/oauth/token",
+//		ClientID:     os.Getenv("CLIENT_ID"),
+//		ClientSecret: os.Getenv("CLIENT_SECRET"),
+//	}, os.Getenv("REFRESH_TOKEN"))
+func RefreshToken(cfg OAuth2Config, refreshToken string) TokenSource {
+	return &cachedTokenSource{
+		leeway: cfg.leeway(),
+		fetch: func(ctx context.Context, current *Token) (*Token, error) {
+			if current != nil && current.RefreshToken != "" {
+				refreshToken = current.RefreshToken
+			}
+			form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}}
+			token, err := cfg.requestToken(ctx, form)
+			if err != nil {
+				return nil, err
+			}
+			if token.RefreshToken == "" {
+				token.RefreshToken = refreshToken
+			}
+			return token, nil
+		},
+	}
+}
+
+// StaticToken returns a TokenSource that always returns the same access token.
+func StaticToken(accessToken string) TokenSource {
+	return &cachedTokenSource{token: &Token{AccessToken: accessToken, TokenType: "Bearer"}}
+}
+
+func (cfg OAuth2Config) leeway() time.Duration {
+	if cfg.ExpiryLeeway > 0 {
+		return cfg.ExpiryLeeway
+	}
+	return 30 * time.Second
+}
+
+// requestToken posts a grant to the token endpoint
+func (cfg OAuth2Config) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	for key, values := range cfg.EndpointParams {
+		form[key] = values
+	}
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+
+	headers := map[string]string{"Accept": "application/json"}
+	if cfg.AuthInBody {
+		form.Set("client_id", cfg.ClientID)
+		if cfg.ClientSecret != "" {
+			form.Set("client_secret", cfg.ClientSecret)
+		}
+	} else {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+		headers["Authorization"] = req.Header.Get("Authorization")
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = tokenClient
+	}
+
+	var token Token
+	if err := PostCtx(ctx, client, cfg.TokenURL, &token, FormBody(form), nil, headers, DecodeWith(JSONDecoder)); err != nil {
+		return nil, fmt.Errorf("Error Fetching OAuth2 Token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("Error Fetching OAuth2 Token: response has no access_token")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// cachedTokenSource returns the cached token until it is about to expire.
+// Concurrent callers wait for a single fetch rather than each fetching their own.
+type cachedTokenSource struct {
+	mu       sync.Mutex
+	token    *Token
+	leeway   time.Duration
+	fetch    func(ctx context.Context, current *Token) (*Token, error)
+	fetching *tokenFetch // the fetch in flight, nil when there is none
+}
+
+// fetchingTokenKey marks the context of token endpoint requests, which OAuth2 leaves
+// alone even when they are sent by a client authenticating with the same source
+type fetchingTokenKey struct{}
+
+// tokenFetch is a token fetch that callers of Token wait on
+type tokenFetch struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	if s.token.Valid(s.leeway) || s.fetch == nil {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	// the lock is not held during the fetch, callers wait on it instead
+	fetch := s.fetching
+	if fetch == nil {
+		fetch = &tokenFetch{done: make(chan struct{})}
+		s.fetching = fetch
+		// one caller giving up must not fail the fetch for the others waiting on it
+		go s.run(context.WithValue(context.WithoutCancel(ctx), fetchingTokenKey{}, true), fetch, s.token)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+		return fetch.token, fetch.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run fetches a new token, caches it and hands it to everyone waiting on fetch
+func (s *cachedTokenSource) run(ctx context.Context, fetch *tokenFetch, current *Token) {
+	fetch.token, fetch.err = s.fetch(ctx, current)
+
+	s.mu.Lock()
+	if fetch.err == nil {
+		s.token = fetch.token
+	}
+	s.fetching = nil
+	s.mu.Unlock()
+	close(fetch.done)
+}
+
+// invalidate drops the cached token so the next call fetches a new one
+func (s *cachedTokenSource) invalidate(stale *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == stale && s.fetch != nil {
+		s.token = nil
+	}
+}
+
+// OAuth2 sets "Authorization: <type> <token>" from ts on every request, but not on redirects to
+// another host. If the server answers 401 and the source caches tokens, the token is dropped
+// and the request is sent once more with a freshly fetched one.
+func OAuth2(ts TokenSource) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if redirectedAway(req) || req.Context().Value(fetchingTokenKey{}) != nil {
+				return next.RoundTrip(req)
+			}
+			token, err := ts.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.RoundTrip(setHeader(req, "Authorization", authorization(token)))
+			cached, ok := ts.(*cachedTokenSource)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized || !ok || cached.fetch == nil {
+				return resp, err
+			}
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return resp, err
+			}
+
+			// token was revoked or expired early, try once more with a new one
+			cached.invalidate(token)
+			if token, err = ts.Token(req.Context()); err != nil {
+				return resp, nil
+			}
+			retry := setHeader(req, "Authorization", authorization(token))
+			if req.GetBody != nil {
+				if retry.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+			resp.Body.Close()
+			return next.RoundTrip(retry)
+		})
+	}
+}
+
+func authorization(token *Token) string {
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + token.AccessToken
+}
+
+// WithTokenSource authenticates every request of the client with tokens from ts.
+// It is shorthand for WithMiddleware(OAuth2(ts)).
+func WithTokenSource(ts TokenSource) Option {
+	return WithMiddleware(OAuth2(ts))
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer issues access tokens "token-1", "token-2", ... valid for expiresIn seconds
+func tokenServer(t *testing.T, expiresIn int, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("ParseForm: %v", err)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "id" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		time.Sleep(delay)
+		n := issued.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d, "refresh_token": "refresh-%d"}`, n, expiresIn, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func TestClientCredentialsCachesToken(t *testing.T) {
+	srv, issued := tokenServer(t, 3600, 0)
+	ts := ClientCredentials(OAuth2Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	for range 3 {
+		token, err := ts.Token(context.Background())
+		if err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+		if token.AccessToken != "token-1" || token.Expiry.IsZero() {
+			t.Fatalf("got %+v, want token-1 with an expiry", token)
+		}
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("fetched %d tokens, want 1", issued.Load())
+	}
+}
+
+func TestClientCredentialsRefreshesBeforeExpiry(t *testing.T) {
+	// tokens expire within the 30s leeway, so every call fetches a new one
+	srv, issued := tokenServer(t, 10, 0)
+	ts := ClientCredentials(OAuth2Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	first, _ := ts.Token(context.Background())
+	second, err := ts.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if first.AccessToken == second.AccessToken || issued.Load() != 2 {
+		t.Fatalf("got %s then %s after %d fetches, want a refresh", first.AccessToken, second.AccessToken, issued.Load())
+	}
+}
+
+func TestRefreshTokenUsesRotatedToken(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sent = append(sent, r.PostForm.Get("refresh_token"))
+		fmt.Fprintf(w, `{"access_token": "a", "expires_in": 1, "refresh_token": "rotated-%d"}`, len(sent))
+	}))
+	defer srv.Close()
+	ts := RefreshToken(OAuth2Config{TokenURL: srv.URL, ClientID: "id"}, "initial")
+
+	for range 3 {
+		if _, err := ts.Token(context.Background()); err != nil {
+			t.Fatalf("Token: %v", err)
+		}
+	}
+	if fmt.Sprint(sent) != "[initial rotated-1 rotated-2]" {
+		t.Fatalf("sent refresh tokens %v", sent)
+	}
+}
+
+func TestConcurrentTokenCallsShareOneFetch(t *testing.T) {
+	srv, issued := tokenServer(t, 3600, 50*time.Millisecond)
+	ts := ClientCredentials(OAuth2Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := ts.Token(context.Background()); err != nil || token.AccessToken != "token-1" {
+				t.Errorf("Token = %v, %v", token, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if issued.Load() != 1 {
+		t.Fatalf("fetched %d tokens, want 1", issued.Load())
+	}
+}
+
+func TestTokenWaiterHonorsContext(t *testing.T) {
+	srv, _ := tokenServer(t, 3600, 200*time.Millisecond)
+	ts := ClientCredentials(OAuth2Config{TokenURL: srv.URL, ClientID: "id", ClientSecret: "secret"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ts.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	// the fetch carries on for the next caller
+	if token, err := ts.Token(context.Background()); err != nil || token.AccessToken != "token-1" {
+		t.Fatalf("Token = %v, %v", token, err)
+	}
+}
+
+func TestTokenSourceOnDefaultClientDoesNotDeadlock(t *testing.T) {
+	tokens, _ := tokenServer(t, 3600, 0)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	previous := DefaultClient
+	defer func() { DefaultClient = previous }()
+	ts := ClientCredentials(OAuth2Config{TokenURL: tokens.URL, ClientID: "id", ClientSecret: "secret"})
+	DefaultClient = NewClient(WithTokenSource(ts))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var auth string
+	if err := GetCtx(ctx, DefaultClient, api.URL, &auth, nil, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if auth != "Bearer token-1" {
+		t.Fatalf("Authorization = %q", auth)
+	}
+}
+
+func TestTokenClientWithSameSourceDoesNotDeadlock(t *testing.T) {
+	tokens, _ := tokenServer(t, 3600, 0)
+	cfg := OAuth2Config{TokenURL: tokens.URL, ClientID: "id", ClientSecret: "secret"}
+	var ts TokenSource
+	client := NewClient(WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return OAuth2(ts)(next)
+	}))
+	cfg.Client = client
+	ts = ClientCredentials(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if token, err := ts.Token(ctx); err != nil || token.AccessToken != "token-1" {
+		t.Fatalf("Token = %v, %v", token, err)
+	}
+}
+
+func TestOAuth2RetriesUnauthorizedWithNewToken(t *testing.T) {
+	tokens, issued := tokenServer(t, 3600, 0)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized) // revoked
+			return
+		}
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	ts := ClientCredentials(OAuth2Config{TokenURL: tokens.URL, ClientID: "id", ClientSecret: "secret"})
+	var auth string
+	if err := Post(NewClient(WithTokenSource(ts)), api.URL, &auth, map[string]int{"n": 1}, nil, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if auth != "Bearer token-2" || issued.Load() != 2 {
+		t.Fatalf("Authorization = %q after %d tokens", auth, issued.Load())
+	}
+}
+
+func TestOAuth2IsNotSentOnCrossHostRedirects(t *testing.T) {
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("Authorization")
+	}))
+	defer other.Close()
+	origin := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+	defer origin.Close()
+
+	client := NewClient(WithTokenSource(StaticToken("secret")))
+	if err := Get[any](client, origin.URL, nil, nil, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if leaked != "" {
+		t.Fatalf("token sent to another host: %q", leaked)
+	}
+}
@@ -0,0 +1,361 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	ratelimiter "github.com/jkrebs-tr/goUtils/rateLimiter"
+)
+
+// PageRequest is the template for the requests made by Paginate. Strategies
+// update Params (or URL) between pages; the template itself is not modified.
+type PageRequest struct {
+	Method  string // defaults to GET
+	URL     string
+	Body    any
+	Params  map[string]string
+	Headers map[string]string
+
+	ItemsPath   string                   // dot separated path to the item array in the body (e.g. "data.items"), empty for a top-level array
+	MaxPages    int                      // stop after this many pages, 0 for no limit
+	RateLimiter *ratelimiter.RateLimiter // optional, waited on before every page
+}
+
+// Page describes a fetched page and is handed to a PageStrategy to work out the next one.
+type Page struct {
+	Number int      // 1-based page count
+	URL    *url.URL // the URL the page was fetched from, after redirects
+	Header http.Header
+	Body   []byte
+	Items  int // number of items decoded from the page
+}
+
+// PageStrategy moves a PageRequest from one page to the next.
+type PageStrategy interface {
+	// Start prepares the request for the first page.
+	Start(req *PageRequest)
+	// Next prepares the request for the page after page, returning false when there are no more pages.
+	Next(req *PageRequest, page Page) (bool, error)
+}
+
+// PageNumber paginates with a page number query parameter (?page=1&per_page=100).
+// It stops on an empty page or, when Size is set, a page with fewer than Size items.
+type PageNumber struct {
+	Param     string // defaults to "page"
+	First     int    // number of the first page, defaults to 1 (0 with ZeroBased)
+	ZeroBased bool   // pages are numbered from 0, as in Spring's Pageable
+	SizeParam string // optional page size parameter, e.g. "per_page"
+	Size      int
+}
+
+func (p PageNumber) param() string {
+	if p.Param == "" {
+		return "page"
+	}
+	return p.Param
+}
+
+func (p PageNumber) Start(req *PageRequest) {
+	first := p.First
+	if first == 0 && !p.ZeroBased {
+		first = 1
+	}
+	req.Params[p.param()] = strconv.Itoa(first)
+	if p.SizeParam != "" && p.Size > 0 {
+		req.Params[p.SizeParam] = strconv.Itoa(p.Size)
+	}
+}
+
+func (p PageNumber) Next(req *PageRequest, page Page) (bool, error) {
+	if page.Items == 0 || (p.Size > 0 && page.Items < p.Size) {
+		return false, nil
+	}
+	current, err := strconv.Atoi(req.Params[p.param()])
+	if err != nil {
+		return false, fmt.Errorf("Invalid Page Number: %w", err)
+	}
+	req.Params[p.param()] = strconv.Itoa(current + 1)
+	return true, nil
+}
+
+// Offset paginates with offset/limit query parameters (?offset=200&limit=100).
+// It stops on an empty page or a page with fewer than Limit items.
+type Offset struct {
+	Param      string // defaults to "offset"
+	LimitParam string // defaults to "limit"
+	Limit      int
+}
+
+func (o Offset) params() (string, string) {
+	param, limitParam := o.Param, o.LimitParam
+	if param == "" {
+		param = "offset"
+	}
+	if limitParam == "" {
+		limitParam = "limit"
+	}
+	return param, limitParam
+}
+
+func (o Offset) Start(req *PageRequest) {
+	param, limitParam := o.params()
+	req.Params[param] = "0"
+	if o.Limit > 0 {
+		req.Params[limitParam] = strconv.Itoa(o.Limit)
+	}
+}
+
+func (o Offset) Next(req *PageRequest, page Page) (bool, error) {
+	if page.Items == 0 || (o.Limit > 0 && page.Items < o.Limit) {
+		return false, nil
+	}
+	param, _ := o.params()
+	current, err := strconv.Atoi(req.Params[param])
+	if err != nil {
+		return false, fmt.Errorf("Invalid Offset: %w", err)
+	}
+	req.Params[param] = strconv.Itoa(current + page.Items)
+	return true, nil
+}
+
+// Cursor paginates by reading the next cursor from the response body and
+// sending it back as a query parameter. It stops when the cursor is missing, null or empty.
+type Cursor struct {
+	Field string // dot separated path to the cursor in the body, e.g. "meta.next_cursor"
+	Param string // query parameter the cursor is sent in, e.g. "cursor"
+}
+
+func (cur Cursor) Start(req *PageRequest) {}
+
+func (cur Cursor) Next(req *PageRequest, page Page) (bool, error) {
+	// a missing cursor ends pagination, a body that isn't JSON is an error
+	raw, err := jsonAtPath(page.Body, cur.Field)
+	if err != nil {
+		return false, err
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return false, nil
+	}
+
+	// numbers are kept as written, large IDs would lose precision as float64
+	var next any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&next); err != nil {
+		return false, fmt.Errorf("Error Reading Cursor: %w", err)
+	}
+	var cursor string
+	switch v := next.(type) {
+	case string:
+		cursor = v
+	case json.Number:
+		cursor = v.String()
+	default:
+		return false, fmt.Errorf("Unsupported Cursor Type: %T", next)
+	}
+	if cursor == "" {
+		return false, nil
+	}
+
+	req.Params[cur.Param] = cursor
+	return true, nil
+}
+
+// LinkHeader paginates by following the RFC 5988 Link header with rel="next", as used by GitHub and others.
+// Relative links are resolved against the URL of the page that returned them.
+type LinkHeader struct{}
+
+func (LinkHeader) Start(req *PageRequest) {}
+
+func (LinkHeader) Next(req *PageRequest, page Page) (bool, error) {
+	next := nextLink(page.Header.Values("Link"))
+	if next == "" {
+		return false, nil
+	}
+	if page.URL != nil {
+		target, err := page.URL.Parse(next)
+		if err != nil {
+			return false, fmt.Errorf("Invalid Link Header: %w", err)
+		}
+		next = target.String()
+	}
+	// the link carries its own query string
+	req.URL = next
+	clear(req.Params)
+	return true, nil
+}
+
+// nextLink finds the rel="next" target in Link header values
+func nextLink(values []string) string {
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, attr := range parts[1:] {
+				key, val, _ := strings.Cut(strings.TrimSpace(attr), "=")
+				if strings.EqualFold(key, "rel") && slices.ContainsFunc(strings.Fields(strings.Trim(val, `"`)), isNextRel) {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func isNextRel(rel string) bool {
+	return strings.EqualFold(rel, "next")
+}
+
+// jsonAtPath returns the raw JSON value at a dot separated path, or the whole body for an empty path
+func jsonAtPath(body []byte, path string) (json.RawMessage, error) {
+	raw := json.RawMessage(body)
+	if path == "" {
+		return raw, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("Error Reading Path %q: %w", path, err)
+		}
+		next, ok := obj[key]
+		if !ok {
+			return nil, nil
+		}
+		raw = next
+	}
+	return raw, nil
+}
+
+// Paginate fetches pages using the client and strategy, yielding every item decoded from them.
+// Errors are yielded once with a zero item, after which iteration stops.
+//
+// Parameters:
+//   - ctx: Context for every page request
+//   - c: The client to send the requests with
+//   - template: The request for the first page plus ItemsPath, MaxPages and RateLimiter settings
+//   - strategy: How to move between pages (PageNumber, Offset, Cursor, LinkHeader or your own)
+//
+// Returns:
+//   - iter.Seq2[T, error]: The items across all pages
+//
+// Example usage:
+//
+//	// cursor in the body: {"data": [...], "meta": {"next": "abc"}}
+//	users := Paginate[User](ctx, client, PageRequest{
+//		URL:       "/users",
+//		Params:    map[string]string{"limit": "100"},
+//		ItemsPath: "data",
+//		MaxPages:  50,
+//	}, Cursor{Field: "meta.next", Param: "cursor"})
+//
+//	for user, err := range users {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(user.Name)
+//	}
+//
+//	// GitHub style Link headers, 5 requests per second
+//	repos := Paginate[Repo](ctx, client, PageRequest{
+//		URL:         "https://api.github.com/orgs/golang/repos",
+//		RateLimiter: ratelimiter.NewRateLimiter(5),
+//	}, LinkHeader{})
+func Paginate[T any](ctx context.Context, c *Client, template PageRequest, strategy PageStrategy) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		req := template
+		req.Params = maps.Clone(template.Params)
+		if req.Params == nil {
+			req.Params = make(map[string]string)
+		}
+		if req.Method == "" {
+			req.Method = http.MethodGet
+		}
+		strategy.Start(&req)
+
+		for number := 1; req.MaxPages <= 0 || number <= req.MaxPages; number++ {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			if req.RateLimiter != nil {
+				if err := req.RateLimiter.WaitCtx(ctx); err != nil {
+					yield(zero, err)
+					return
+				}
+			}
+
+			pageURL, header, body, err := c.fetchPage(ctx, &req)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			raw, err := jsonAtPath(body, req.ItemsPath)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			var items []T
+			if len(raw) > 0 {
+				if err := json.Unmarshal(raw, &items); err != nil {
+					yield(zero, fmt.Errorf("Error Unmarshaling Page %d: %w", number, err))
+					return
+				}
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			more, err := strategy.Next(&req, Page{Number: number, URL: pageURL, Header: header, Body: body, Items: len(items)})
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// fetchPage sends a single page request and returns its final URL, headers and body
+func (c *Client) fetchPage(ctx context.Context, req *PageRequest) (*url.URL, http.Header, []byte, error) {
+	httpReq, err := c.newRequest(ctx, req.Method, req.URL, req.Body, req.Params, req.Headers)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	response, err := c.execute(httpReq)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Error Reading Response Body: %w", err)
+	}
+	pageURL := httpReq.URL
+	if response.Request != nil {
+		pageURL = response.Request.URL
+	}
+	return pageURL, response.Header, body, nil
+}
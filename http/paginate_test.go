@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ratelimiter "github.com/jkrebs-tr/goUtils/rateLimiter"
+)
+
+func TestCursorKeepsLargeNumbers(t *testing.T) {
+	req := PageRequest{Params: map[string]string{}}
+	more, err := Cursor{Field: "next", Param: "after"}.Next(&req, Page{Body: []byte(`{"next": 9007199254740993}`)})
+	if err != nil || !more {
+		t.Fatalf("Next = %v, %v", more, err)
+	}
+	if got := req.Params["after"]; got != "9007199254740993" {
+		t.Fatalf("cursor = %q, want 9007199254740993", got)
+	}
+}
+
+func TestCursorMalformedBody(t *testing.T) {
+	req := PageRequest{Params: map[string]string{}}
+	if more, err := (Cursor{Field: "meta.next", Param: "after"}).Next(&req, Page{Body: []byte(`<html>bad gateway</html>`)}); err == nil || more {
+		t.Errorf("Next on a non-JSON body = %v, %v, want an error", more, err)
+	}
+	if more, err := (Cursor{Field: "meta.next", Param: "after"}).Next(&req, Page{Body: []byte(`{"meta": {}}`)}); err != nil || more {
+		t.Errorf("Next without a cursor = %v, %v, want the end of pagination", more, err)
+	}
+}
+
+func TestPageNumberFirst(t *testing.T) {
+	tests := []struct {
+		strategy PageNumber
+		want     string
+	}{
+		{PageNumber{}, "1"},
+		{PageNumber{ZeroBased: true}, "0"},
+		{PageNumber{First: 3}, "3"},
+		{PageNumber{First: 3, ZeroBased: true}, "3"},
+	}
+	for _, tt := range tests {
+		req := PageRequest{Params: map[string]string{}}
+		tt.strategy.Start(&req)
+		if got := req.Params["page"]; got != tt.want {
+			t.Errorf("%+v starts at page %q, want %q", tt.strategy, got, tt.want)
+		}
+	}
+}
+
+func TestLinkHeaderResolvesRelativeLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/items", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `<items?page=2>; rel="next"`)
+			fmt.Fprint(w, `[1, 2]`)
+		case "2":
+			w.Header().Set("Link", `</api/v2/items?page=3>; rel="next"`)
+			fmt.Fprint(w, `[3]`)
+		default:
+			fmt.Fprint(w, `[4]`)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	client := NewClient(WithBaseURL(srv.URL))
+	var got []int
+	for item, err := range Paginate[int](context.Background(), client, PageRequest{URL: "/api/v2/items"}, LinkHeader{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, item)
+	}
+	if fmt.Sprint(got) != "[1 2 3 4]" {
+		t.Fatalf("got %v, want [1 2 3 4]", got)
+	}
+}
+
+func TestPaginateRateLimiterHonorsContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[1]`)
+	}))
+	defer srv.Close()
+
+	limiter := ratelimiter.NewRateLimiter(1)
+	limiter.Wait() // empty the bucket so the first page has to wait a second
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	for _, err := range Paginate[int](ctx, NewClient(), PageRequest{URL: srv.URL, RateLimiter: limiter}, PageNumber{}) {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want context.DeadlineExceeded", err)
+		}
+		return
+	}
+	t.Fatal("no error yielded")
+}
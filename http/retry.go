@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries failed requests.
+//
+// A request is retried when the transport returns an error accepted by RetryIf,
+// or when the response status is one of RetryableStatusCodes. Delays grow
+// exponentially from BaseDelay up to MaxDelay, with Jitter randomizing each delay.
+// A Retry-After header on a retryable response overrides the computed delay.
+//
+// Only idempotent methods (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) are retried
+// unless RetryNonIdempotent is set or the request carries an Idempotency-Key header.
+type RetryPolicy struct {
+	MaxAttempts          int              // total attempts including the first, <= 1 disables retries
+	BaseDelay            time.Duration    // delay before the second attempt, 0 retries without waiting
+	MaxDelay             time.Duration    // upper bound for any single delay, 0 means unbounded
+	Jitter               float64          // fraction (0-1) of each delay that is randomized
+	RetryableStatusCodes []int            // response codes that trigger a retry
+	RetryIf              func(error) bool // transport errors that trigger a retry, nil retries all but context errors
+	RetryNonIdempotent   bool             // also retry POST, PATCH and other non-idempotent methods
+}
+
+// DefaultRetryPolicy returns a policy with 3 attempts, 200ms base delay, 5s max delay,
+// 20% jitter, and retries on 408, 425, 429, 500, 502, 503 and 504 responses.
+//
+// Example usage:
+//
+//	policy := DefaultRetryPolicy()
+//	policy.MaxAttempts = 5
+//	client := NewClient(WithRetry(policy))
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooEarly,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetry enables retries with exponential backoff using the given policy.
+//
+// Example usage:
+//
+//	// retry POSTs as well, the endpoint de-duplicates on its own
+//	policy := DefaultRetryPolicy()
+//	policy.RetryNonIdempotent = true
+//	client := NewClient(WithBaseURL("https://api.example.com"), WithRetry(policy))
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = &policy
+	}
+}
+
+// canRetry reports whether the request's method may be retried under the policy
+func (p *RetryPolicy) canRetry(req *http.Request) bool {
+	if p.MaxAttempts <= 1 {
+		return false
+	}
+	// a streamed body can't be replayed
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if p.RetryNonIdempotent || req.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether the outcome of an attempt warrants another one
+func (p *RetryPolicy) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		// an open circuit will still be open after the backoff
+		if errors.Is(err, ErrCircuitOpen) {
+			return false
+		}
+		if p.RetryIf != nil {
+			return p.RetryIf(err)
+		}
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return slices.Contains(p.RetryableStatusCodes, resp.StatusCode)
+}
+
+// delay returns how long to wait after the given (1-based) failed attempt
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if p.MaxDelay > 0 && wait > p.MaxDelay {
+				wait = p.MaxDelay
+			}
+			return wait
+		}
+	}
+
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	// double per attempt, stopping before the duration would overflow
+	d := p.BaseDelay
+	for i := 1; i < attempt && d <= math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		spread := time.Duration(float64(d) * min(p.Jitter, 1))
+		d = d - spread + time.Duration(rand.Int64N(int64(spread)+1))
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// send performs the request, retrying according to the client's retry policy
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	policy := c.retry
+	if policy == nil || !policy.canRetry(req) {
+		return c.httpClient.Do(req)
+	}
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := c.httpClient.Do(attemptReq)
+		if attempt >= policy.MaxAttempts || !policy.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		wait := policy.delay(attempt, resp)
+		if resp != nil {
+			// drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetry is a policy with delays short enough for tests
+func fastRetry(attempts int) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = attempts
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 10 * time.Millisecond
+	policy.Jitter = 0
+	return policy
+}
+
+// failingServer answers the first failures requests with status and then 200 {"ok":true}
+func failingServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryRecoversFromRetryableStatus(t *testing.T) {
+	srv, calls := failingServer(t, 2, http.StatusServiceUnavailable, nil)
+	client := NewClient(WithRetry(fastRetry(3)))
+
+	var res struct{ OK bool }
+	if err := Get(client, srv.URL, &res, nil, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !res.OK || calls.Load() != 3 {
+		t.Fatalf("got ok=%v after %d calls, want ok after 3", res.OK, calls.Load())
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := failingServer(t, 10, http.StatusBadGateway, nil)
+	client := NewClient(WithRetry(fastRetry(3)))
+
+	err := Get[any](client, srv.URL, nil, nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got %v, want a 502 HTTPError", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("got %d calls, want 3", calls.Load())
+	}
+}
+
+func TestRetrySkipsNonRetryableStatus(t *testing.T) {
+	srv, calls := failingServer(t, 10, http.StatusBadRequest, nil)
+	client := NewClient(WithRetry(fastRetry(3)))
+
+	if err := Get[any](client, srv.URL, nil, nil, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("got %d calls, want 1", calls.Load())
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  func(*RetryPolicy)
+		headers map[string]string
+		want    int32
+	}{
+		{name: "post is not retried", want: 1},
+		{name: "idempotency key", headers: map[string]string{"Idempotency-Key": "abc"}, want: 2},
+		{name: "retry non-idempotent", policy: func(p *RetryPolicy) { p.RetryNonIdempotent = true }, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := failingServer(t, 1, http.StatusServiceUnavailable, nil)
+			policy := fastRetry(3)
+			if tt.policy != nil {
+				tt.policy(&policy)
+			}
+			client := NewClient(WithRetry(policy))
+
+			Post[any](client, srv.URL, nil, map[string]string{"a": "b"}, nil, tt.headers)
+			if calls.Load() != tt.want {
+				t.Fatalf("got %d calls, want %d", calls.Load(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	var bodies []string
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64)
+		n, _ := r.Body.Read(buf)
+		bodies = append(bodies, string(buf[:n]))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	client := NewClient(WithRetry(fastRetry(2)))
+
+	if err := Do[any](client, "PUT", srv.URL, nil, map[string]int{"n": 1}, nil, nil); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] != `{"n":1}` {
+		t.Fatalf("got bodies %q, want the same body twice", bodies)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv, calls := failingServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	policy := fastRetry(2)
+	policy.MaxDelay = 5 * time.Second
+	client := NewClient(WithRetry(policy))
+
+	start := time.Now()
+	if err := Get[any](client, srv.URL, nil, nil, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("retried after %v, want the 1s Retry-After", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("got %d calls, want 2", calls.Load())
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	srv, _ := failingServer(t, 10, http.StatusServiceUnavailable, http.Header{"Retry-After": {"30"}})
+	policy := fastRetry(3)
+	policy.MaxDelay = time.Minute
+	client := NewClient(WithRetry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := GetCtx[any](ctx, client, srv.URL, nil, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("backoff did not stop on cancellation")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		if got := policy.delay(i+1, nil); got != w {
+			t.Errorf("delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.delay(2, nil); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("jittered delay %v outside [100ms, 200ms]", got)
+		}
+	}
+}
+
+func TestRetryDelayEdges(t *testing.T) {
+	// no base delay means no backoff, not MaxDelay
+	policy := RetryPolicy{MaxDelay: 30 * time.Second, Jitter: 0.2}
+	for attempt := 1; attempt <= 5; attempt++ {
+		if got := policy.delay(attempt, nil); got != 0 {
+			t.Errorf("delay(%d) = %v with no BaseDelay, want 0", attempt, got)
+		}
+	}
+
+	// unbounded backoff saturates instead of overflowing back to 0
+	policy = RetryPolicy{BaseDelay: time.Second}
+	prev := time.Duration(0)
+	for _, attempt := range []int{1, 10, 34, 35, 64, 65, 200} {
+		got := policy.delay(attempt, nil)
+		if got <= 0 || got < prev {
+			t.Errorf("delay(%d) = %v, want it to keep growing from %v", attempt, got, prev)
+		}
+		prev = got
+	}
+}
+
+func TestRetryDelayUsesRetryAfter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+	resp := &http.Response{Header: http.Header{"Retry-After": {"3"}}}
+	if got := policy.delay(1, resp); got != 3*time.Second {
+		t.Errorf("delay = %v, want 3s", got)
+	}
+
+	resp.Header.Set("Retry-After", "120")
+	if got := policy.delay(1, resp); got != 10*time.Second {
+		t.Errorf("delay = %v, want it capped at MaxDelay", got)
+	}
+
+	resp.Header.Set("Retry-After", "soon")
+	if got := policy.delay(1, resp); got != 100*time.Millisecond {
+		t.Errorf("delay = %v, want the backoff for an unparsable Retry-After", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("5"); !ok || d != 5*time.Second {
+		t.Errorf("seconds: got %v %v", d, ok)
+	}
+	date := time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d < time.Minute || d > 2*time.Minute {
+		t.Errorf("date: got %v %v", d, ok)
+	}
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(past); !ok || d != 0 {
+		t.Errorf("past date: got %v %v", d, ok)
+	}
+	for _, value := range []string{"", "-1", "tomorrow"} {
+		if _, ok := parseRetryAfter(value); ok {
+			t.Errorf("%q parsed", value)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEEvent is a single Server-Sent Event.
+type SSEEvent struct {
+	ID    string        // last event ID seen on the stream
+	Type  string        // event type, "message" if the server sent none
+	Data  string        // data lines joined with "\n"
+	Retry time.Duration // reconnection delay the server sent with this event, if any
+}
+
+// TypedEvent is an SSEEvent whose data was decoded as JSON into a T.
+type TypedEvent[T any] struct {
+	SSEEvent
+	Value T
+}
+
+// SSERequest describes a text/event-stream request.
+type SSERequest struct {
+	Method  string // defaults to GET
+	URL     string
+	Body    any
+	Params  map[string]string
+	Headers map[string]string
+
+	LastEventID   string        // resume after this event ID
+	MaxReconnects int           // reconnect this many times after the stream drops, -1 for no limit, 0 never
+	RetryDelay    time.Duration // delay before reconnecting unless the server sends retry, defaults to 3s
+	DoneData      string        // stop when an event's data equals this, e.g. "[DONE]" for OpenAI-style streams
+}
+
+// Events opens a Server-Sent Events stream and yields its events as they arrive.
+// When the stream drops it reconnects (up to MaxReconnects times) sending Last-Event-ID,
+// waiting the server's retry delay in between. Reconnects that fail are retried the same
+// way and count towards MaxReconnects; only a failed first connect ends the stream at once.
+// A 204 No Content response ends the stream.
+//
+// Errors are yielded once with a zero event, after which iteration stops. Cancel ctx or
+// break out of the loop to close the stream. The client's timeout is not applied, since
+// streams are long-lived; use ctx for deadlines.
+//
+// Example usage:
+//
+//	stream := Events(ctx, client, SSERequest{URL: "/v1/notifications", MaxReconnects: -1})
+//	for event, err := range stream {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(event.Type, event.Data)
+//	}
+func Events(ctx context.Context, c *Client, req SSERequest) iter.Seq2[SSEEvent, error] {
+	return func(yield func(SSEEvent, error) bool) {
+		lastEventID := req.LastEventID
+		retry := req.RetryDelay
+		if retry <= 0 {
+			retry = 3 * time.Second
+		}
+
+		for attempt := 0; ; attempt++ {
+			body, err := c.openEventStream(ctx, req, lastEventID)
+			if errors.Is(err, errStreamEnded) {
+				return
+			}
+			if err != nil && attempt == 0 {
+				yield(SSEEvent{}, err)
+				return
+			}
+
+			// a failed reconnect is retried like a dropped stream
+			if err == nil {
+				stopped := false
+				err = readEvents(body, lastEventID, &retry, func(event SSEEvent) bool {
+					lastEventID = event.ID
+					if req.DoneData != "" && event.Data == req.DoneData {
+						stopped = true
+						return false
+					}
+					if !yield(event, nil) {
+						stopped = true
+						return false
+					}
+					return true
+				})
+				body.Close()
+				if err != nil {
+					err = fmt.Errorf("Error Reading Event Stream: %w", err)
+				}
+
+				if stopped {
+					return
+				}
+			}
+			if ctx.Err() != nil {
+				yield(SSEEvent{}, ctx.Err())
+				return
+			}
+			if req.MaxReconnects >= 0 && attempt >= req.MaxReconnects {
+				if err != nil {
+					yield(SSEEvent{}, err)
+				}
+				return
+			}
+
+			timer := time.NewTimer(retry)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				yield(SSEEvent{}, ctx.Err())
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// TypedEvents is like Events but decodes each event's data as JSON into a T.
+//
+// Example usage:
+//
+//	// OpenAI-style streaming
+//	stream := TypedEvents[Chunk](ctx, client, SSERequest{
+//		Method:   "POST",
+//		URL:      "/v1/chat/completions",
+//		Body:     request,
+//		DoneData: "[DONE]",
+//	})
+//	for event, err := range stream {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(event.Value.Choices[0].Delta.Content)
+//	}
+func TypedEvents[T any](ctx context.Context, c *Client, req SSERequest) iter.Seq2[TypedEvent[T], error] {
+	return func(yield func(TypedEvent[T], error) bool) {
+		for event, err := range Events(ctx, c, req) {
+			if err != nil {
+				yield(TypedEvent[T]{}, err)
+				return
+			}
+			typed := TypedEvent[T]{SSEEvent: event}
+			if err := json.Unmarshal([]byte(event.Data), &typed.Value); err != nil {
+				yield(typed, fmt.Errorf("Error Unmarshaling Event %q: %w", event.Type, err))
+				return
+			}
+			if !yield(typed, nil) {
+				return
+			}
+		}
+	}
+}
+
+// EventChannel delivers the events of an iterator on a channel, closing it when the
+// iterator ends or ctx is done. Errors are sent on the second channel, which has room for one.
+func EventChannel[T any](ctx context.Context, events iter.Seq2[T, error]) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(out)
+		defer close(errs)
+		for event, err := range events {
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, errs
+}
+
+// errStreamEnded is returned when the server answers 204 to stop the client reconnecting
+var errStreamEnded = errors.New("event stream ended")
+
+// openEventStream sends the request and returns the stream body
+func (c *Client) openEventStream(ctx context.Context, req SSERequest, lastEventID string) (io.ReadCloser, error) {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	headers := maps.Clone(req.Headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers["Accept"] = "text/event-stream"
+	headers["Cache-Control"] = "no-cache"
+	if lastEventID != "" {
+		headers["Last-Event-ID"] = lastEventID
+	}
+
+	httpReq, err := c.newRequest(skipCache(ctx), method, req.URL, req.Body, req.Params, headers)
+	if err != nil {
+		return nil, err
+	}
+	if req.Body == nil {
+		httpReq.Header.Del("Content-Type")
+	}
+
+	response, err := c.streamingClient().Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("Error Making Request: %w", err)
+	}
+	if response.StatusCode == http.StatusNoContent {
+		response.Body.Close()
+		return nil, errStreamEnded
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		defer response.Body.Close()
+		return nil, newHTTPError(response)
+	}
+	return response.Body, nil
+}
+
+// readEvents parses a text/event-stream body, calling dispatch for every event until
+// dispatch returns false or the body ends. retry is updated as soon as the server sends one.
+func readEvents(body io.Reader, lastEventID string, retry *time.Duration, dispatch func(SSEEvent) bool) error {
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	event := SSEEvent{ID: lastEventID}
+	hasData := false
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// a blank line dispatches the buffered event
+		if line == "" {
+			if hasData {
+				event.Data = strings.TrimSuffix(data.String(), "\n")
+				if event.Type == "" {
+					event.Type = "message"
+				}
+				if !dispatch(event) {
+					return nil
+				}
+			}
+			data.Reset()
+			hasData = false
+			event = SSEEvent{ID: event.ID}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "event":
+			event.Type = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				event.ID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				event.Retry = time.Duration(ms) * time.Millisecond
+				*retry = event.Retry
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sseServer calls serve for every connection with its 1-based number, recording the Last-Event-ID sent
+func sseServer(t *testing.T, serve func(w http.ResponseWriter, conn int)) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var lastIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		conn := len(lastIDs)
+		mu.Unlock()
+		serve(w, conn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), lastIDs...)
+	}
+}
+
+func streamEvents(req SSERequest, url string) ([]SSEEvent, error) {
+	req.URL = url
+	var events []SSEEvent
+	for event, err := range Events(context.Background(), NewClient(), req) {
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func TestEventsParsesStream(t *testing.T) {
+	srv, _ := sseServer(t, func(w http.ResponseWriter, _ int) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\nid: 1\nevent: greeting\ndata: hello\ndata: world\n\nretry: 1500\ndata: {\"n\": 2}\n\ndata: [DONE]\n\n")
+	})
+
+	events, err := streamEvents(SSERequest{DoneData: "[DONE]"}, srv.URL)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events: %+v", len(events), events)
+	}
+	if e := events[0]; e.ID != "1" || e.Type != "greeting" || e.Data != "hello\nworld" {
+		t.Errorf("first event %+v", e)
+	}
+	if e := events[1]; e.ID != "1" || e.Type != "message" || e.Data != `{"n": 2}` || e.Retry != 1500*time.Millisecond {
+		t.Errorf("second event %+v", e)
+	}
+}
+
+func TestEventsRetriesFailedReconnects(t *testing.T) {
+	srv, lastIDs := sseServer(t, func(w http.ResponseWriter, conn int) {
+		switch conn {
+		case 1:
+			fmt.Fprint(w, "id: 1\ndata: one\n\n") // then the stream drops
+		case 2, 3:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 4:
+			fmt.Fprint(w, "id: 2\ndata: two\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent) // done
+		}
+	})
+
+	events, err := streamEvents(SSERequest{MaxReconnects: -1, RetryDelay: time.Millisecond}, srv.URL)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(events) != 2 || events[1].Data != "two" {
+		t.Fatalf("got %+v", events)
+	}
+	if got := fmt.Sprint(lastIDs()); got != "[ 1 1 1 2]" {
+		t.Fatalf("Last-Event-ID per connection %s", got)
+	}
+}
+
+func TestEventsCountsFailedReconnects(t *testing.T) {
+	srv, lastIDs := sseServer(t, func(w http.ResponseWriter, conn int) {
+		if conn == 1 {
+			fmt.Fprint(w, "data: one\n\n")
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	events, err := streamEvents(SSERequest{MaxReconnects: 2, RetryDelay: time.Millisecond}, srv.URL)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got %v, want the failed reconnect's 502", err)
+	}
+	if len(events) != 1 || len(lastIDs()) != 3 {
+		t.Fatalf("got %d events over %d connections, want 1 over 3", len(events), len(lastIDs()))
+	}
+}
+
+func TestEventsFailedFirstConnectEndsStream(t *testing.T) {
+	srv, lastIDs := sseServer(t, func(w http.ResponseWriter, _ int) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if _, err := streamEvents(SSERequest{MaxReconnects: -1, RetryDelay: time.Millisecond}, srv.URL); !IsUnauthorized(err) {
+		t.Fatalf("got %v, want 401", err)
+	}
+	if len(lastIDs()) != 1 {
+		t.Fatalf("connected %d times, want 1", len(lastIDs()))
+	}
+}
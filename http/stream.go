@@ -0,0 +1,220 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"strings"
+)
+
+// StreamAt makes Stream read the array at a dot separated path in the body
+// (e.g. "data.items") instead of a top-level array.
+func StreamAt(path string) RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.streamPath = path
+	}
+}
+
+// StreamNDJSON makes Stream read newline delimited JSON regardless of the response Content-Type.
+// Responses with an NDJSON, JSON Lines or JSON text sequence (RFC 7464) Content-Type are
+// detected automatically.
+func StreamNDJSON() RequestOption {
+	return func(cfg *requestConfig) {
+		cfg.ndjson = true
+	}
+}
+
+// ndjsonTypes are response media types streamed as one JSON value per line
+var ndjsonTypes = []string{"application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines"}
+
+// jsonSeqType is RFC 7464 JSON text sequences, values each preceded by a record separator
+const jsonSeqType = "application/json-seq"
+
+// recordSeparators turns the RS bytes of a JSON text sequence into newlines. RS can't occur
+// unescaped inside JSON, so every one found is a separator.
+type recordSeparators struct {
+	r io.Reader
+}
+
+func (rs recordSeparators) Read(p []byte) (int, error) {
+	n, err := rs.r.Read(p)
+	for i, b := range p[:n] {
+		if b == 0x1E {
+			p[i] = '\n'
+		}
+	}
+	return n, err
+}
+
+// Stream sends a request and decodes the elements of a JSON array response one at a time,
+// yielding each as it is read so the whole body is never held in memory. It reads a
+// top-level array by default, the array at a path with StreamAt, or newline delimited
+// JSON with StreamNDJSON (or an NDJSON Content-Type).
+//
+// Errors are yielded once with a zero item, after which iteration stops. Breaking out
+// of the loop closes the response. The client's timeout covers reading the whole body,
+// so long exports may need a client created with WithTimeout(0) and a ctx deadline instead.
+// Streamed requests bypass a client's WithCache, which would have to read the whole body.
+//
+// Parameters:
+//   - ctx: Context for the request, including reading the body
+//   - c: The client to send the request with
+//   - method: HTTP method
+//   - url: The target URL, absolute or relative to the client's base URL
+//   - body: Request body, encoded as in Do
+//   - params: Query parameters as key-value pairs
+//   - headers: HTTP headers as key-value pairs
+//   - opts: Per-request options such as StreamAt, StreamNDJSON or UseMiddleware
+//
+// Returns:
+//   - iter.Seq2[T, error]: The array elements
+//
+// Example usage:
+//
+//	// {"export": {"rows": [ ...millions of rows... ]}}
+//	rows := Stream[Row](ctx, client, "GET", "/exports/42", nil, nil, nil, StreamAt("export.rows"))
+//	for row, err := range rows {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		process(row)
+//	}
+func Stream[T any](ctx context.Context, c *Client, method string, url string, body any, params map[string]string, headers map[string]string, opts ...RequestOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		cfg := newRequestConfig(opts)
+		ctx := skipCache(withRequestMiddleware(ctx, cfg.middleware))
+
+		req, err := c.newRequest(ctx, method, url, body, params, headers)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		response, err := c.execute(req)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer response.Body.Close()
+
+		mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+		if mediaType == jsonSeqType {
+			streamValues(json.NewDecoder(recordSeparators{response.Body}), yield)
+			return
+		}
+		dec := json.NewDecoder(response.Body)
+
+		if cfg.ndjson || containsFold(ndjsonTypes, mediaType) {
+			streamValues(dec, yield)
+			return
+		}
+
+		if err := seekArray(dec, cfg.streamPath); err != nil {
+			yield(zero, err)
+			return
+		}
+		for dec.More() {
+			var item T
+			if err := dec.Decode(&item); err != nil {
+				yield(zero, fmt.Errorf("Error Decoding Stream Element: %w", err))
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			yield(zero, fmt.Errorf("Error Decoding Stream: %w", err))
+		}
+	}
+}
+
+// streamValues yields consecutive JSON values until EOF
+func streamValues[T any](dec *json.Decoder, yield func(T, error) bool) {
+	var zero T
+	for {
+		var item T
+		err := dec.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			yield(zero, fmt.Errorf("Error Decoding Stream Element: %w", err))
+			return
+		}
+		if !yield(item, nil) {
+			return
+		}
+	}
+}
+
+// seekArray advances dec to just inside the array at path
+func seekArray(dec *json.Decoder, path string) error {
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			if err := seekKey(dec, key); err != nil {
+				return fmt.Errorf("Error Finding %q: %w", path, err)
+			}
+		}
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("Error Decoding Stream: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("Error Decoding Stream: expected array, got %v", tok)
+	}
+	return nil
+}
+
+// seekKey enters the next object and advances to the value of key, skipping other members
+func seekKey(dec *json.Decoder, key string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if name, _ := tok.(string); name == key {
+			return nil
+		}
+		if err := skipValue(dec); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("key %q not found", key)
+}
+
+// skipValue reads past the next value token by token, without buffering it
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
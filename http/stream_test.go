@@ -0,0 +1,73 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func streamInts(t *testing.T, c *Client, url string, opts ...RequestOption) []int {
+	t.Helper()
+	var got []int
+	for item, err := range Stream[int](context.Background(), c, "GET", url, nil, nil, nil, opts...) {
+		if err != nil {
+			t.Fatalf("Stream: %v", err)
+		}
+		got = append(got, item)
+	}
+	return got
+}
+
+func TestStreamFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []RequestOption
+	}{
+		{name: "array", contentType: "application/json", body: `[1, 2, 3]`},
+		{name: "nested array", contentType: "application/json", body: `{"meta": {"n": [9]}, "data": {"rows": [1, 2, 3]}}`, opts: []RequestOption{StreamAt("data.rows")}},
+		{name: "ndjson", contentType: "application/x-ndjson", body: "1\n2\n3\n"},
+		{name: "forced ndjson", contentType: "text/plain", body: "1\n2\n3", opts: []RequestOption{StreamNDJSON()}},
+		{name: "json-seq", contentType: "application/json-seq", body: "\x1e1\n\x1e2\n\x1e3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			if got := streamInts(t, NewClient(), srv.URL, tt.opts...); fmt.Sprint(got) != "[1 2 3]" {
+				t.Fatalf("got %v, want [1 2 3]", got)
+			}
+		})
+	}
+}
+
+func TestStreamBypassesCache(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		fmt.Fprint(w, `[1, 2, 3]`)
+	}))
+	defer srv.Close()
+
+	cache := NewCache(NewMemoryCache(10))
+	client := NewClient(WithCache(cache))
+	streamInts(t, client, srv.URL)
+	streamInts(t, client, srv.URL)
+
+	if calls.Load() != 2 {
+		t.Fatalf("server called %d times, want every stream to reach it", calls.Load())
+	}
+	if stats := cache.Stats(); stats != (CacheStats{}) {
+		t.Fatalf("cache saw the streams: %+v", stats)
+	}
+}
@@ -1,19 +1,23 @@
 package http
 
 type GraphQLRequest struct {
-	Query     string                 `json:"query"`
-	Variables map[string]any `json:"variables,omitempty"`
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
 }
 
 type GraphQLResponse[T any] struct {
-	Data   T                        `json:"data"`
-	Errors []GraphQLError           `json:"errors,omitempty"`
+	Data       T              `json:"data"`
+	Errors     []GraphQLError `json:"errors,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
 }
 
 type GraphQLError struct {
-	Message   string                 `json:"message"`
-	Path      []any          `json:"path,omitempty"`
-	Locations []GraphQLErrorLocation `json:"locations,omitempty"`
+	Message    string                 `json:"message"`
+	Path       []any                  `json:"path,omitempty"`
+	Locations  []GraphQLErrorLocation `json:"locations,omitempty"`
+	Extensions map[string]any         `json:"extensions,omitempty"`
 }
 
 type GraphQLErrorLocation struct {
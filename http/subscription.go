@@ -0,0 +1,381 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// graphqlTransportWS is the graphql-transport-ws subprotocol (graphql-ws library)
+const graphqlTransportWS = "graphql-transport-ws"
+
+// wsMessage is a graphql-transport-ws protocol message
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// SubscriptionClient runs GraphQL subscriptions over WebSocket using the
+// graphql-transport-ws protocol. Every subscription uses its own connection,
+// which is re-established (and the subscription resent) if it drops.
+type SubscriptionClient struct {
+	client          *Client
+	url             string
+	headers         map[string]string
+	initPayload     func(ctx context.Context) (map[string]any, error)
+	ackTimeout      time.Duration
+	keepAlive       time.Duration
+	reconnect       RetryPolicy
+	ids             atomic.Int64
+	onConnectionErr func(error)
+}
+
+// SubscriptionOption configures a SubscriptionClient.
+type SubscriptionOption func(*SubscriptionClient)
+
+// WithInitPayload sets the connection_init payload, typically used for auth.
+func WithInitPayload(payload map[string]any) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.initPayload = func(context.Context) (map[string]any, error) { return payload, nil }
+	}
+}
+
+// WithInitPayloadFunc builds the connection_init payload on every (re)connect,
+// e.g. to include a freshly fetched token.
+func WithInitPayloadFunc(fn func(ctx context.Context) (map[string]any, error)) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.initPayload = fn
+	}
+}
+
+// WithSubscriptionHeaders sets headers sent with the WebSocket handshake.
+func WithSubscriptionHeaders(headers map[string]string) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.headers = headers
+	}
+}
+
+// WithKeepAlive sends a ping every interval (default 30s, 0 disables it).
+func WithKeepAlive(interval time.Duration) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.keepAlive = interval
+	}
+}
+
+// WithReconnect sets how dropped connections are retried. Only MaxAttempts, BaseDelay,
+// MaxDelay and Jitter are used; MaxAttempts counts consecutive failed connects.
+// The default makes up to 5 attempts with 1s to 30s backoff.
+func WithReconnect(policy RetryPolicy) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.reconnect = policy
+	}
+}
+
+// OnConnectionError is called with every connection error that leads to a reconnect.
+func OnConnectionError(fn func(error)) SubscriptionOption {
+	return func(sc *SubscriptionClient) {
+		sc.onConnectionErr = fn
+	}
+}
+
+// NewSubscriptionClient creates a SubscriptionClient for the endpoint at url (ws://, wss://,
+// http:// or https://), opening connections with c (DefaultClient if nil).
+//
+// Example usage:
+//
+//	subs := NewSubscriptionClient(client, "wss://api.example.com/graphql",
+//		WithInitPayload(map[string]any{"authToken": token}),
+//	)
+//
+//	query := `subscription OnOrder { orderCreated { id total } }`
+//	for event, err := range Subscribe[OrderEvent](ctx, subs, GraphQLRequest{Query: query}) {
+//		if err != nil {
+//			log.Printf("subscription error: %v", err)
+//			continue
+//		}
+//		fmt.Println(event.OrderCreated.ID)
+//	}
+func NewSubscriptionClient(c *Client, url string, opts ...SubscriptionOption) *SubscriptionClient {
+	if c == nil {
+		c = DefaultClient
+	}
+	sc := &SubscriptionClient{
+		client:     c,
+		url:        url,
+		ackTimeout: 10 * time.Second,
+		keepAlive:  30 * time.Second,
+		reconnect: RetryPolicy{
+			MaxAttempts: 5,
+			BaseDelay:   time.Second,
+			MaxDelay:    30 * time.Second,
+			Jitter:      0.2,
+		},
+	}
+	for _, opt := range opts {
+		opt(sc)
+	}
+	return sc
+}
+
+// errSubscriptionComplete ends a subscription the server completed
+var errSubscriptionComplete = errors.New("subscription complete")
+
+// Subscribe starts a subscription and yields the data of every "next" message.
+// Payloads carrying GraphQL errors are yielded with their (partial) data and a
+// GraphQLErrors error, and the subscription continues. An "error" message, a
+// failed reconnect or ctx being done ends the iteration; the server completing
+// the subscription ends it without an error.
+//
+// If the connection drops, it is re-established with backoff and the subscription
+// is sent again.
+func Subscribe[T any](ctx context.Context, sc *SubscriptionClient, req GraphQLRequest) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		failures := 0
+
+		for {
+			delivered := false
+			err := sc.run(ctx, req, func(payload json.RawMessage) bool {
+				delivered = true
+				var gqlRes GraphQLResponse[T]
+				if err := json.Unmarshal(payload, &gqlRes); err != nil {
+					return yield(zero, fmt.Errorf("Error Unmarshaling Subscription Payload: %w", err))
+				}
+				if len(gqlRes.Errors) > 0 {
+					return yield(gqlRes.Data, GraphQLErrors(gqlRes.Errors))
+				}
+				return yield(gqlRes.Data, nil)
+			})
+
+			var gqlErrs GraphQLErrors
+			switch {
+			case err == nil, errors.Is(err, errSubscriptionComplete):
+				return
+			case ctx.Err() != nil:
+				yield(zero, ctx.Err())
+				return
+			case errors.As(err, &gqlErrs):
+				// the server rejected the operation, resubscribing won't help
+				yield(zero, err)
+				return
+			}
+
+			if sc.onConnectionErr != nil {
+				sc.onConnectionErr(err)
+			}
+			if delivered {
+				failures = 0
+			}
+			failures++
+			if failures >= sc.reconnect.MaxAttempts {
+				yield(zero, fmt.Errorf("GraphQL subscription failed after %d attempts: %w", failures, err))
+				return
+			}
+
+			timer := time.NewTimer(sc.reconnect.delay(failures, nil))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				yield(zero, ctx.Err())
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// run holds one connection for one subscription, calling deliver for every "next"
+// payload. It returns nil when deliver asks to stop.
+func (sc *SubscriptionClient) run(ctx context.Context, req GraphQLRequest, deliver func(json.RawMessage) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ws, err := sc.client.dialWebSocket(ctx, sc.url, graphqlTransportWS, sc.headers)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	// unblock reads once ctx is done
+	go func() {
+		<-ctx.Done()
+		ws.shutdown()
+	}()
+
+	if err := sc.handshake(ctx, ws); err != nil {
+		return err
+	}
+
+	id := strconv.FormatInt(sc.ids.Add(1), 10)
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("Error Marshaling Subscription: %w", err)
+	}
+	if err := writeWSMessage(ws, wsMessage{ID: id, Type: "subscribe", Payload: payload}); err != nil {
+		return err
+	}
+
+	// pings and pongs are handled by the reader, so a consumer slow to take the next
+	// message can't hold up a pong and make a healthy connection look dead
+	var awaitingPong, handingOff atomic.Bool
+	messages := make(chan wsMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := readWSMessage(ws)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			switch msg.Type {
+			case "ping":
+				if err := writeWSMessage(ws, wsMessage{Type: "pong"}); err != nil {
+					readErr <- err
+					return
+				}
+				continue
+			case "pong":
+				awaitingPong.Store(false)
+				continue
+			}
+			handingOff.Store(true)
+			select {
+			case messages <- msg:
+				handingOff.Store(false)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var keepAlive <-chan time.Time
+	if sc.keepAlive > 0 {
+		ticker := time.NewTicker(sc.keepAlive)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-readErr:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+
+		case <-keepAlive:
+			if awaitingPong.Load() {
+				// a pong may be queued behind a message the reader is still handing over
+				if handingOff.Load() {
+					continue
+				}
+				return fmt.Errorf("GraphQL subscription: no pong within %s", sc.keepAlive)
+			}
+			awaitingPong.Store(true)
+			if err := writeWSMessage(ws, wsMessage{Type: "ping"}); err != nil {
+				return err
+			}
+
+		case msg := <-messages:
+			switch msg.Type {
+			case "next":
+				if msg.ID == id && !deliver(msg.Payload) {
+					// caller stopped, tell the server we're done
+					writeWSMessage(ws, wsMessage{ID: id, Type: "complete"})
+					return nil
+				}
+			case "error":
+				if msg.ID == id {
+					var errs GraphQLErrors
+					if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) == 0 {
+						errs = GraphQLErrors{{Message: string(msg.Payload)}}
+					}
+					return errs
+				}
+			case "complete":
+				if msg.ID == id {
+					return errSubscriptionComplete
+				}
+			}
+		}
+	}
+}
+
+// handshake sends connection_init and waits for connection_ack
+func (sc *SubscriptionClient) handshake(ctx context.Context, ws *wsConn) error {
+	init := wsMessage{Type: "connection_init"}
+	if sc.initPayload != nil {
+		payload, err := sc.initPayload(ctx)
+		if err != nil {
+			return fmt.Errorf("Error Building Init Payload: %w", err)
+		}
+		if init.Payload, err = json.Marshal(payload); err != nil {
+			return fmt.Errorf("Error Marshaling Init Payload: %w", err)
+		}
+	}
+	if err := writeWSMessage(ws, init); err != nil {
+		return err
+	}
+
+	acked := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := readWSMessage(ws)
+			if err != nil {
+				acked <- err
+				return
+			}
+			switch msg.Type {
+			case "connection_ack":
+				acked <- nil
+				return
+			case "ping":
+				writeWSMessage(ws, wsMessage{Type: "pong"})
+			}
+		}
+	}()
+
+	timer := time.NewTimer(sc.ackTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-acked:
+		if err != nil {
+			return fmt.Errorf("GraphQL subscription handshake failed: %w", err)
+		}
+		return nil
+	case <-timer.C:
+		ws.shutdown()
+		return fmt.Errorf("GraphQL subscription handshake failed: no connection_ack within %s", sc.ackTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeWSMessage(ws *wsConn, msg wsMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return ws.WriteText(data)
+}
+
+func readWSMessage(ws *wsConn) (wsMessage, error) {
+	data, err := ws.ReadMessage()
+	if err != nil {
+		return wsMessage{}, err
+	}
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return wsMessage{}, fmt.Errorf("Error Unmarshaling WebSocket Message: %w", err)
+	}
+	return msg, nil
+}
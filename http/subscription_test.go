@@ -0,0 +1,296 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// wsTestServer upgrades every request to a WebSocket and hands it to serve along with
+// the 1-based connection number
+func wsTestServer(t *testing.T, serve func(ws *wsConn, conn int)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns atomic.Int32
+	var handlers sync.WaitGroup
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers.Add(1)
+		defer handlers.Done()
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+			http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+			return
+		}
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n" +
+			"Sec-WebSocket-Protocol: " + r.Header.Get("Sec-WebSocket-Protocol") + "\r\n\r\n"))
+		ws := newWSConn(conn, false)
+		defer ws.shutdown()
+		serve(ws, int(conns.Add(1)))
+	}))
+	t.Cleanup(srv.Close)
+	// hijacked connections outlive srv.Close, wait for them before the test ends
+	t.Cleanup(handlers.Wait)
+	return srv, &conns
+}
+
+// expectWS reads the next message, which must be of the given type
+func expectWS(t *testing.T, ws *wsConn, msgType string) wsMessage {
+	t.Helper()
+	msg, err := readWSMessage(ws)
+	if err != nil {
+		t.Errorf("reading %s: %v", msgType, err)
+		return wsMessage{}
+	}
+	if msg.Type != msgType {
+		t.Errorf("got %s message, want %s", msg.Type, msgType)
+	}
+	return msg
+}
+
+// acceptSubscription acks the connection and returns the subscribe message
+func acceptSubscription(t *testing.T, ws *wsConn) wsMessage {
+	t.Helper()
+	expectWS(t, ws, "connection_init")
+	writeWSMessage(ws, wsMessage{Type: "connection_ack"})
+	return expectWS(t, ws, "subscribe")
+}
+
+func sendNext(ws *wsConn, id string, data string) {
+	writeWSMessage(ws, wsMessage{ID: id, Type: "next", Payload: json.RawMessage(`{"data": ` + data + `}`)})
+}
+
+type counter struct {
+	Count int `json:"count"`
+}
+
+func collect(t *testing.T, seq func(func(counter, error) bool)) ([]int, error) {
+	t.Helper()
+	var got []int
+	for item, err := range seq {
+		if err != nil {
+			return got, err
+		}
+		got = append(got, item.Count)
+	}
+	return got, nil
+}
+
+func TestSubscriptionHandshake(t *testing.T) {
+	srv, _ := wsTestServer(t, func(ws *wsConn, _ int) {
+		init := expectWS(t, ws, "connection_init")
+		if string(init.Payload) != `{"authToken":"secret"}` {
+			t.Errorf("init payload %s", init.Payload)
+		}
+		writeWSMessage(ws, wsMessage{Type: "connection_ack"})
+		sub := expectWS(t, ws, "subscribe")
+		var req GraphQLRequest
+		json.Unmarshal(sub.Payload, &req)
+		if req.Query != "subscription { count }" {
+			t.Errorf("subscribed to %q", req.Query)
+		}
+		sendNext(ws, sub.ID, `{"count": 1}`)
+		sendNext(ws, sub.ID, `{"count": 2}`)
+		writeWSMessage(ws, wsMessage{ID: sub.ID, Type: "complete"})
+		if msg, err := readWSMessage(ws); err == nil {
+			t.Errorf("client sent %s after the subscription completed", msg.Type)
+		}
+	})
+
+	sc := NewSubscriptionClient(NewClient(), strings.Replace(srv.URL, "http://", "ws://", 1),
+		WithInitPayload(map[string]any{"authToken": "secret"}))
+	got, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"}))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+}
+
+func TestSubscriptionStopsOnServerError(t *testing.T) {
+	srv, _ := wsTestServer(t, func(ws *wsConn, _ int) {
+		sub := acceptSubscription(t, ws)
+		writeWSMessage(ws, wsMessage{ID: sub.ID, Type: "error", Payload: json.RawMessage(`[{"message": "unknown field"}]`)})
+		readWSMessage(ws)
+	})
+
+	sc := NewSubscriptionClient(NewClient(), srv.URL)
+	_, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { nope }"}))
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || gqlErrs[0].Message != "unknown field" {
+		t.Fatalf("got %v, want the server's GraphQLErrors", err)
+	}
+}
+
+func TestSubscriptionReconnectsAndResubscribes(t *testing.T) {
+	srv, conns := wsTestServer(t, func(ws *wsConn, conn int) {
+		sub := acceptSubscription(t, ws)
+		if conn == 1 {
+			sendNext(ws, sub.ID, `{"count": 1}`)
+			return // drop the connection
+		}
+		sendNext(ws, sub.ID, `{"count": 2}`)
+		writeWSMessage(ws, wsMessage{ID: sub.ID, Type: "complete"})
+		readWSMessage(ws)
+	})
+
+	var connErrs atomic.Int32
+	sc := NewSubscriptionClient(NewClient(), srv.URL,
+		WithReconnect(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+		OnConnectionError(func(error) { connErrs.Add(1) }))
+	got, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"}))
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if len(got) != 2 || conns.Load() != 2 || connErrs.Load() != 1 {
+		t.Fatalf("got %v over %d connections with %d errors", got, conns.Load(), connErrs.Load())
+	}
+}
+
+func TestSubscriptionGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, conns := wsTestServer(t, func(ws *wsConn, _ int) {
+		// reject the handshake
+		expectWS(t, ws, "connection_init")
+	})
+
+	sc := NewSubscriptionClient(NewClient(), srv.URL,
+		WithReconnect(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	_, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"}))
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("got %v", err)
+	}
+	if conns.Load() != 3 {
+		t.Fatalf("connected %d times, want 3", conns.Load())
+	}
+}
+
+func TestSubscriptionKeepAliveDetectsDeadConnection(t *testing.T) {
+	srv, _ := wsTestServer(t, func(ws *wsConn, _ int) {
+		acceptSubscription(t, ws)
+		// read pings but never answer them
+		for {
+			if _, err := readWSMessage(ws); err != nil {
+				return
+			}
+		}
+	})
+
+	sc := NewSubscriptionClient(NewClient(), srv.URL, WithKeepAlive(20*time.Millisecond),
+		WithReconnect(RetryPolicy{MaxAttempts: 1}))
+	_, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"}))
+	if err == nil || !strings.Contains(err.Error(), "no pong") {
+		t.Fatalf("got %v, want a keepalive failure", err)
+	}
+}
+
+func TestSubscriptionKeepAliveToleratesSlowConsumer(t *testing.T) {
+	const messages = 8
+	srv, conns := wsTestServer(t, func(ws *wsConn, _ int) {
+		sub := acceptSubscription(t, ws)
+		sendNext(ws, sub.ID, `{"count": 1}`)
+		// answer every ping with the next message first, so the pong queues up behind it
+		for count := 2; ; count++ {
+			msg, err := readWSMessage(ws)
+			if err != nil {
+				return
+			}
+			if msg.Type == "ping" {
+				if count <= messages {
+					sendNext(ws, sub.ID, fmt.Sprintf(`{"count": %d}`, count))
+				}
+				writeWSMessage(ws, wsMessage{Type: "pong"})
+			}
+		}
+	})
+
+	sc := NewSubscriptionClient(NewClient(), srv.URL, WithKeepAlive(20*time.Millisecond),
+		WithReconnect(RetryPolicy{MaxAttempts: 1}))
+	got := 0
+	for _, err := range Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"}) {
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		time.Sleep(60 * time.Millisecond) // several keepalive intervals
+		if got++; got == messages {
+			break
+		}
+	}
+	if conns.Load() != 1 {
+		t.Fatalf("reconnected %d times for a slow consumer", conns.Load()-1)
+	}
+}
+
+func TestSubscriptionAnswersServerPings(t *testing.T) {
+	srv, _ := wsTestServer(t, func(ws *wsConn, _ int) {
+		sub := acceptSubscription(t, ws)
+		writeWSMessage(ws, wsMessage{Type: "ping"})
+		expectWS(t, ws, "pong")
+		writeWSMessage(ws, wsMessage{ID: sub.ID, Type: "complete"})
+		readWSMessage(ws)
+	})
+
+	sc := NewSubscriptionClient(NewClient(), srv.URL)
+	if _, err := collect(t, Subscribe[counter](context.Background(), sc, GraphQLRequest{Query: "subscription { count }"})); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+}
+
+func TestWebSocketFraming(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	client, server := newWSConn(clientConn, true), newWSConn(serverConn, false)
+	defer client.shutdown()
+	defer server.shutdown()
+
+	for _, size := range []int{0, 125, 126, 65535, 65536, 200000} {
+		message := bytes.Repeat([]byte("x"), size)
+		go client.WriteText(message)
+		got, err := server.ReadMessage()
+		if err != nil || !bytes.Equal(got, message) {
+			t.Fatalf("%d byte message: got %d bytes, %v", size, len(got), err)
+		}
+	}
+
+	// a fragmented message with a ping between the fragments, sent unmasked by the server
+	go func() {
+		serverConn.Write(rawFrame(false, wsText, "hello "))
+		serverConn.Write(rawFrame(true, wsPing, "p"))
+		serverConn.Write(rawFrame(true, wsContinuation, "world"))
+	}()
+	pong := make(chan []byte, 1)
+	go func() {
+		_, opcode, payload, _ := server.readFrame()
+		if opcode == wsPong {
+			pong <- payload
+		}
+	}()
+	got, err := client.ReadMessage()
+	if err != nil || string(got) != "hello world" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if p := <-pong; string(p) != "p" {
+		t.Fatalf("pong payload %q", p)
+	}
+}
+
+// rawFrame encodes a short unmasked frame
+func rawFrame(fin bool, opcode byte, payload string) []byte {
+	head := opcode
+	if fin {
+		head |= 0x80
+	}
+	return append([]byte{head, byte(len(payload))}, payload...)
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by WebhookConfig.Verify.
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+	ErrInvalidTimestamp = errors.New("webhook: missing or invalid timestamp")
+	ErrTimestampExpired = errors.New("webhook: timestamp outside tolerance")
+
+	// ErrWebhookConfig means the WebhookConfig itself is unusable, e.g. it has no Secret.
+	// Every webhook is rejected until it is fixed.
+	ErrWebhookConfig = errors.New("webhook: invalid config")
+)
+
+// WebhookConfig describes how a vendor signs its webhooks.
+//
+// The signature is the HMAC of the raw body with Secret, hex encoded (or base64 with
+// Base64), in SignatureHeader, optionally after a prefix such as "sha256=". The
+// timestamp in TimestampHeader is checked against Tolerance to stop replays and,
+// unless SignedPayload says otherwise, signed as "<timestamp>.<body>".
+//
+// Secret is required, and so is TimestampHeader unless SkipTimestamp is set for a
+// vendor that doesn't send one. Verify fails with ErrWebhookConfig otherwise.
+type WebhookConfig struct {
+	Secret          string
+	SignatureHeader string        // defaults to "X-Signature"
+	SignaturePrefix string        // stripped from the header value, e.g. "sha256="
+	SHA1            bool          // use HMAC-SHA1 instead of HMAC-SHA256
+	Base64          bool          // signatures are base64 instead of hex encoded
+	TimestampHeader string        // header with a unix (seconds) or RFC 3339 timestamp
+	SkipTimestamp   bool          // accept webhooks without a timestamp, which leaves replays undetected
+	Tolerance       time.Duration // max clock difference for TimestampHeader, defaults to 5m
+	MaxBodySize     int64         // larger bodies are rejected with 413, defaults to 1MB
+
+	// SignedPayload builds the signed bytes when the default doesn't match the vendor
+	SignedPayload func(timestamp string, body []byte) []byte
+	// OnError is called for every rejected request, e.g. for logging
+	OnError func(r *http.Request, status int, err error)
+}
+
+// Verify checks the signature and timestamp of a webhook body.
+// It returns one of ErrMissingSignature, ErrInvalidSignature, ErrInvalidTimestamp
+// or ErrTimestampExpired, or ErrWebhookConfig when cfg has no Secret or TimestampHeader.
+func (cfg WebhookConfig) Verify(header http.Header, body []byte) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+
+	signature := strings.TrimSpace(header.Get(cfg.signatureHeader()))
+	signature = strings.TrimPrefix(signature, cfg.SignaturePrefix)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	payload := body
+	if cfg.TimestampHeader != "" {
+		timestamp := strings.TrimSpace(header.Get(cfg.TimestampHeader))
+		sent, err := parseWebhookTimestamp(timestamp)
+		if err != nil {
+			return ErrInvalidTimestamp
+		}
+		tolerance := cfg.Tolerance
+		if tolerance <= 0 {
+			tolerance = 5 * time.Minute
+		}
+		if age := time.Since(sent); age > tolerance || age < -tolerance {
+			return ErrTimestampExpired
+		}
+		if cfg.SignedPayload == nil {
+			payload = append([]byte(timestamp+"."), body...)
+		} else {
+			payload = cfg.SignedPayload(timestamp, body)
+		}
+	} else if cfg.SignedPayload != nil {
+		payload = cfg.SignedPayload("", body)
+	}
+
+	var got []byte
+	var err error
+	if cfg.Base64 {
+		got, err = base64.StdEncoding.DecodeString(signature)
+	} else {
+		got, err = hex.DecodeString(signature)
+	}
+	if err != nil || !hmac.Equal(got, cfg.sign(payload)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// validate fails closed on configs that would accept forged or replayed webhooks
+func (cfg WebhookConfig) validate() error {
+	if cfg.Secret == "" {
+		return fmt.Errorf("%w: Secret is empty", ErrWebhookConfig)
+	}
+	if cfg.TimestampHeader == "" && !cfg.SkipTimestamp {
+		return fmt.Errorf("%w: TimestampHeader is required, set SkipTimestamp if the vendor sends none", ErrWebhookConfig)
+	}
+	return nil
+}
+
+func (cfg WebhookConfig) sign(payload []byte) []byte {
+	newHash := sha256.New
+	if cfg.SHA1 {
+		newHash = func() hash.Hash { return sha1.New() }
+	}
+	mac := hmac.New(newHash, []byte(cfg.Secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (cfg WebhookConfig) signatureHeader() string {
+	if cfg.SignatureHeader == "" {
+		return "X-Signature"
+	}
+	return cfg.SignatureHeader
+}
+
+// parseWebhookTimestamp accepts unix seconds, unix milliseconds or RFC 3339
+func parseWebhookTimestamp(timestamp string) (time.Time, error) {
+	if n, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, timestamp)
+}
+
+// WebhookHandler returns an http.Handler that verifies incoming webhooks with cfg,
+// decodes their JSON body into a T and calls fn with it.
+//
+// It responds 405 to anything but POST, 413 to bodies over MaxBodySize, 401 to
+// missing or invalid signatures and stale timestamps, 400 to bodies that aren't
+// valid JSON for T, 500 when cfg is invalid or fn returns an error and 204 otherwise.
+// Return an *HTTPError from fn to respond with its status code instead.
+//
+// Example usage:
+//
+//	// GitHub signs the body only, without a timestamp
+//	handler := WebhookHandler(WebhookConfig{
+//		Secret:          os.Getenv("WEBHOOK_SECRET"),
+//		SignatureHeader: "X-Hub-Signature-256",
+//		SignaturePrefix: "sha256=",
+//		SkipTimestamp:   true,
+//	}, func(r *http.Request, event PushEvent) error {
+//		log.Printf("push to %s by %s", event.Repository.FullName, event.Pusher.Name)
+//		return nil
+//	})
+//	http.Handle("/webhooks/github", handler)
+func WebhookHandler[T any](cfg WebhookConfig, fn func(r *http.Request, payload T) error) http.Handler {
+	maxBody := cfg.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = 1 << 20
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reject := func(status int, err error) {
+			if cfg.OnError != nil {
+				cfg.OnError(r, status, err)
+			}
+			http.Error(w, http.StatusText(status), status)
+		}
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			reject(http.StatusMethodNotAllowed, fmt.Errorf("webhook: method %s not allowed", r.Method))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				reject(http.StatusRequestEntityTooLarge, err)
+				return
+			}
+			reject(http.StatusBadRequest, fmt.Errorf("Error Reading Webhook Body: %w", err))
+			return
+		}
+
+		if err := cfg.Verify(r.Header, body); err != nil {
+			if errors.Is(err, ErrWebhookConfig) {
+				reject(http.StatusInternalServerError, err)
+				return
+			}
+			reject(http.StatusUnauthorized, err)
+			return
+		}
+
+		var payload T
+		if err := json.Unmarshal(body, &payload); err != nil {
+			reject(http.StatusBadRequest, fmt.Errorf("Error Unmarshaling Webhook Payload: %w", err))
+			return
+		}
+
+		if err := fn(r, payload); err != nil {
+			var httpErr *HTTPError
+			if errors.As(err, &httpErr) && httpErr.StatusCode >= 400 {
+				reject(httpErr.StatusCode, err)
+				return
+			}
+			reject(http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
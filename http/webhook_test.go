@@ -0,0 +1,124 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type webhookEvent struct {
+	ID string `json:"id"`
+}
+
+func signWebhook(t *testing.T, secret string, payload string) string {
+	t.Helper()
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhook posts body to handler with the given headers and returns the status code
+func sendWebhook(t *testing.T, handler http.Handler, body string, headers map[string]string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookHandlerTimestamp(t *testing.T) {
+	cfg := WebhookConfig{Secret: "shh", TimestampHeader: "X-Timestamp"}
+	body := `{"id":"evt_1"}`
+
+	var got webhookEvent
+	handler := WebhookHandler(cfg, func(r *http.Request, event webhookEvent) error {
+		got = event
+		return nil
+	})
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	status := sendWebhook(t, handler, body, map[string]string{
+		"X-Timestamp": now,
+		"X-Signature": signWebhook(t, "shh", now+"."+body),
+	})
+	if status != http.StatusNoContent || got.ID != "evt_1" {
+		t.Fatalf("status = %d, event = %+v, want 204 with evt_1", status, got)
+	}
+
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	status = sendWebhook(t, handler, body, map[string]string{
+		"X-Timestamp": stale,
+		"X-Signature": signWebhook(t, "shh", stale+"."+body),
+	})
+	if status != http.StatusUnauthorized {
+		t.Errorf("replayed webhook status = %d, want 401", status)
+	}
+
+	status = sendWebhook(t, handler, body, map[string]string{
+		"X-Timestamp": now,
+		"X-Signature": signWebhook(t, "wrong", now+"."+body),
+	})
+	if status != http.StatusUnauthorized {
+		t.Errorf("bad signature status = %d, want 401", status)
+	}
+
+	status = sendWebhook(t, handler, body, map[string]string{"X-Signature": signWebhook(t, "shh", body)})
+	if status != http.StatusUnauthorized {
+		t.Errorf("missing timestamp status = %d, want 401", status)
+	}
+}
+
+func TestWebhookConfigFailsClosed(t *testing.T) {
+	body := `{"id":"evt_1"}`
+	tests := map[string]WebhookConfig{
+		"empty secret":      {TimestampHeader: "X-Timestamp", SkipTimestamp: true},
+		"missing timestamp": {Secret: "shh"},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := cfg.Verify(http.Header{"X-Signature": {signWebhook(t, cfg.Secret, body)}}, []byte(body)); !errors.Is(err, ErrWebhookConfig) {
+				t.Errorf("Verify() = %v, want ErrWebhookConfig", err)
+			}
+
+			called := false
+			handler := WebhookHandler(cfg, func(r *http.Request, event webhookEvent) error {
+				called = true
+				return nil
+			})
+			status := sendWebhook(t, handler, body, map[string]string{"X-Signature": signWebhook(t, cfg.Secret, body)})
+			if status != http.StatusInternalServerError || called {
+				t.Errorf("status = %d, called = %v, want 500 without calling fn", status, called)
+			}
+		})
+	}
+}
+
+func TestWebhookSkipTimestamp(t *testing.T) {
+	cfg := WebhookConfig{
+		Secret:          "shh",
+		SignatureHeader: "X-Hub-Signature-256",
+		SignaturePrefix: "sha256=",
+		SkipTimestamp:   true,
+	}
+	body := `{"id":"evt_1"}`
+	handler := WebhookHandler(cfg, func(r *http.Request, event webhookEvent) error { return nil })
+
+	status := sendWebhook(t, handler, body, map[string]string{"X-Hub-Signature-256": "sha256=" + signWebhook(t, "shh", body)})
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", status)
+	}
+	status = sendWebhook(t, handler, body, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("unsigned status = %d, want 401", status)
+	}
+}
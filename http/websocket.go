@@ -0,0 +1,254 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2)
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsGUID is appended to the handshake key to compute Sec-WebSocket-Accept
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWSMessageSize caps a single incoming message
+const maxWSMessageSize = 32 << 20
+
+// errWSClosed is returned when the peer sends a close frame
+var errWSClosed = errors.New("websocket closed by peer")
+
+// wsConn is a minimal RFC 6455 connection carrying text messages
+type wsConn struct {
+	rw     io.ReadWriteCloser
+	br     *bufio.Reader
+	mask   bool // clients mask every frame they send
+	wmu    sync.Mutex
+	closed bool
+}
+
+func newWSConn(rw io.ReadWriteCloser, mask bool) *wsConn {
+	return &wsConn{rw: rw, br: bufio.NewReader(rw), mask: mask}
+}
+
+// dialWebSocket opens a WebSocket through the client's transport, so TLS, proxy and
+// middleware settings apply to the handshake.
+func (c *Client) dialWebSocket(ctx context.Context, rawURL string, subprotocol string, headers map[string]string) (*wsConn, error) {
+	rawURL = c.resolveURL(rawURL)
+	switch {
+	case strings.HasPrefix(rawURL, "ws://"):
+		rawURL = "http://" + strings.TrimPrefix(rawURL, "ws://")
+	case strings.HasPrefix(rawURL, "wss://"):
+		rawURL = "https://" + strings.TrimPrefix(rawURL, "wss://")
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, rawURL, nil, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Del("Content-Type")
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if subprotocol != "" {
+		req.Header.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+
+	resp, err := c.streamingClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error Opening WebSocket: %w", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		return nil, newHTTPError(resp)
+	}
+
+	rw, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error Opening WebSocket: transport does not support protocol upgrades")
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		rw.Close()
+		return nil, fmt.Errorf("Error Opening WebSocket: invalid Sec-WebSocket-Accept")
+	}
+	if subprotocol != "" && resp.Header.Get("Sec-WebSocket-Protocol") != subprotocol {
+		rw.Close()
+		return nil, fmt.Errorf("Error Opening WebSocket: server did not accept subprotocol %q", subprotocol)
+	}
+
+	return newWSConn(rw, true), nil
+}
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeFrame sends a single final frame
+func (ws *wsConn) writeFrame(opcode byte, payload []byte) error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closed {
+		return net.ErrClosed
+	}
+
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | opcode
+	length := len(payload)
+	switch {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if ws.mask {
+		header[1] |= 0x80
+		key := make([]byte, 4)
+		rand.Read(key)
+		header = append(header, key...)
+		masked := make([]byte, length)
+		for i := range payload {
+			masked[i] = payload[i] ^ key[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := ws.rw.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteText sends a text message.
+func (ws *wsConn) WriteText(data []byte) error {
+	return ws.writeFrame(wsText, data)
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// reassembling fragmented messages along the way.
+func (ws *wsConn) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err := ws.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+		case wsPong:
+		case wsClose:
+			ws.writeFrame(wsClose, payload)
+			ws.shutdown()
+			if len(payload) >= 2 {
+				return nil, fmt.Errorf("%w: %d %s", errWSClosed, binary.BigEndian.Uint16(payload), payload[2:])
+			}
+			return nil, errWSClosed
+		case wsText, wsBinary, wsContinuation:
+			message = append(message, payload...)
+			if len(message) > maxWSMessageSize {
+				return nil, fmt.Errorf("websocket message exceeds %d bytes", maxWSMessageSize)
+			}
+			if fin {
+				return message, nil
+			}
+		default:
+			return nil, fmt.Errorf("websocket: unknown opcode %d", opcode)
+		}
+	}
+}
+
+func (ws *wsConn) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := head[0]&0x80 != 0
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxWSMessageSize {
+		return false, 0, nil, fmt.Errorf("websocket frame exceeds %d bytes", maxWSMessageSize)
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(ws.br, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// Close sends a normal closure frame and closes the connection.
+func (ws *wsConn) Close() error {
+	ws.writeFrame(wsClose, binary.BigEndian.AppendUint16(nil, 1000))
+	return ws.shutdown()
+}
+
+func (ws *wsConn) shutdown() error {
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	if ws.closed {
+		return nil
+	}
+	ws.closed = true
+	return ws.rw.Close()
+}
@@ -1,6 +1,7 @@
 package ratelimiter
 
 import (
+	"context"
 	"sync"
 	"time"
 )
@@ -55,3 +56,16 @@ func (rl *RateLimiter) Wait() {
 	rl.tokenCount--
 	rl.mu.Unlock()
 }
+
+// WaitCtx is like Wait but gives up when ctx is done, returning ctx's error.
+func (rl *RateLimiter) WaitCtx(ctx context.Context) error {
+	select {
+	case <-rl.tokens:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	rl.mu.Lock()
+	rl.tokenCount--
+	rl.mu.Unlock()
+	return nil
+}